@@ -1,6 +1,9 @@
 package clause
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 // Clause contains SQL conditions
 type Clause struct {
@@ -17,11 +20,14 @@ const (
 	VALUES
 	SELECT
 	LIMIT
+	OFFSET
 	WHERE
 	ORDERBY
 	UPDATE
 	DELETE
 	COUNT
+	RETURNING
+	LOCKING
 )
 
 // Set adds a sub clause of specific type.
@@ -38,6 +44,30 @@ func (c *Clause) Set(name Type, vars ...interface{}) {
 	c.sqlVars[name] = vars
 }
 
+// And adds a condition to the WHERE sub clause, joining it with the
+// existing condition (if any) by AND.
+func (c *Clause) And(desc string, vars ...interface{}) {
+	if sql, ok := c.sql[WHERE]; ok {
+		desc = fmt.Sprintf("(%s) AND (%s)", strings.TrimPrefix(sql, "WHERE "), desc)
+		vars = append(append([]interface{}{}, c.sqlVars[WHERE]...), vars...)
+	}
+	c.Set(WHERE, append([]interface{}{desc}, vars...)...)
+}
+
+// Clone returns a deep copy of the clause, so that it can be
+// modified without affecting the original one.
+func (c *Clause) Clone() Clause {
+	clone := Clause{
+		sql:     make(map[Type]string, len(c.sql)),
+		sqlVars: make(map[Type][]interface{}, len(c.sqlVars)),
+	}
+	for name, sql := range c.sql {
+		clone.sql[name] = sql
+		clone.sqlVars[name] = append([]interface{}{}, c.sqlVars[name]...)
+	}
+	return clone
+}
+
 // Build 根据传入 Type 的顺序，构造出最终的 SQL 语句
 func (c *Clause) Build(orders ...Type) (string, []interface{}) {
 	var sqls []string
@@ -20,3 +20,89 @@ func TestSelect(t *testing.T) {
 		t.Fatal("failed to build SQLVars")
 	}
 }
+
+func TestOffset(t *testing.T) {
+	var clause Clause
+	clause.Set(SELECT, "User", []string{"*"})
+	clause.Set(LIMIT, 10)
+	clause.Set(OFFSET, 20)
+	sql, vars := clause.Build(SELECT, LIMIT, OFFSET)
+	if sql != "SELECT * FROM User LIMIT ? OFFSET ?" || !reflect.DeepEqual(vars, []interface{}{10, 20}) {
+		t.Fatal("failed to build OFFSET, got", sql, vars)
+	}
+}
+
+func TestAnd(t *testing.T) {
+	var clause Clause
+	clause.And("Name = ? OR Name = ?", "Tom", "Sam")
+	base := clause.Clone()
+	clause.And("Age > ?", 18)
+	sql, vars := clause.Build(WHERE)
+	if sql != "WHERE (Name = ? OR Name = ?) AND (Age > ?)" {
+		t.Fatal("failed to join conditions, got", sql)
+	}
+	if !reflect.DeepEqual(vars, []interface{}{"Tom", "Sam", 18}) {
+		t.Fatal("failed to join SQLVars, got", vars)
+	}
+	if sql, _ := base.Build(WHERE); sql != "WHERE Name = ? OR Name = ?" {
+		t.Fatal("clone is affected by the original clause, got", sql)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	var clause Clause
+	clause.Set(UPDATE, "User", map[string]interface{}{
+		"Name": "Tom", "Age": Expr{SQL: "Age + ?", Vars: []interface{}{1}}, "Email": "tom@x.com",
+	})
+	sql, vars := clause.Build(UPDATE)
+	if sql != "UPDATE User SET Age = Age + ?, Email = ?, Name = ?" {
+		t.Fatal("failed to build UPDATE, got", sql)
+	}
+	if !reflect.DeepEqual(vars, []interface{}{1, "tom@x.com", "Tom"}) {
+		t.Fatal("failed to build UPDATE vars, got", vars)
+	}
+}
+
+func TestValues(t *testing.T) {
+	var clause Clause
+	clause.Set(VALUES, []interface{}{"Tom", 18}, []interface{}{"Sam", Expr{SQL: "abs(?)", Vars: []interface{}{-25}}})
+	sql, vars := clause.Build(VALUES)
+	if sql != "VALUES (?, ?), (?, abs(?))" || !reflect.DeepEqual(vars, []interface{}{"Tom", 18, "Sam", -25}) {
+		t.Fatal("failed to build VALUES, got", sql, vars)
+	}
+}
+
+func TestReturning(t *testing.T) {
+	var clause Clause
+	clause.Set(DELETE, "User")
+	clause.Set(WHERE, "Age > ?", 18)
+	clause.Set(RETURNING, []string{"Name", "Age"})
+	sql, _ := clause.Build(DELETE, WHERE, RETURNING)
+	if sql != "DELETE FROM User WHERE Age > ? RETURNING Name, Age" {
+		t.Fatal("failed to build RETURNING, got", sql)
+	}
+}
+
+func TestLocking(t *testing.T) {
+	var clause Clause
+	clause.Set(SELECT, "Job", []string{"*"})
+	clause.Set(LIMIT, 1)
+	clause.Set(LOCKING, Locking{Strength: LockingStrengthUpdate, Options: LockingOptionsSkipLocked})
+	sql, _ := clause.Build(SELECT, LIMIT, LOCKING)
+	if sql != "SELECT * FROM Job LIMIT ? FOR UPDATE SKIP LOCKED" {
+		t.Fatal("failed to build locking clause, got", sql)
+	}
+	clause.Set(LOCKING, Locking{Strength: LockingStrengthShare})
+	if sql, _ := clause.Build(LOCKING); sql != "FOR SHARE" {
+		t.Fatal("failed to build locking clause, got", sql)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	sql := Interpolate("SELECT * FROM User WHERE Name = ? AND Note <> '?' AND Age IN (?, ?) AND Data = ? AND Deleted IS ? AND Admin = ?",
+		[]interface{}{"O'Neil", 18, 2.5, []byte("ab"), nil, true})
+	expected := "SELECT * FROM User WHERE Name = 'O''Neil' AND Note <> '?' AND Age IN (18, 2.5) AND Data = X'6162' AND Deleted IS NULL AND Admin = TRUE"
+	if sql != expected {
+		t.Fatal("failed to interpolate vars, got", sql)
+	}
+}
@@ -0,0 +1,206 @@
+package clause
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Condition is a WHERE condition that renders itself to SQL and vars,
+// e.g. And(Eq("Name", "Tom"), In("Age", []int{18, 25})) builds
+// (Name = ?) AND (Age IN (?, ?)) with vars Tom, 18, 25.
+type Condition interface {
+	Build() (string, []interface{})
+}
+
+type condition struct {
+	sql  string
+	vars []interface{}
+}
+
+func (c condition) Build() (string, []interface{}) {
+	return c.sql, c.vars
+}
+
+func compare(column, op string, value interface{}) Condition {
+	return condition{fmt.Sprintf("%s %s ?", column, op), []interface{}{value}}
+}
+
+// Eq builds column = value, or column IS NULL if value is nil.
+func Eq(column string, value interface{}) Condition {
+	if value == nil {
+		return IsNull(column)
+	}
+	return compare(column, "=", value)
+}
+
+// Neq builds column <> value, or column IS NOT NULL if value is nil.
+func Neq(column string, value interface{}) Condition {
+	if value == nil {
+		return condition{column + " IS NOT NULL", nil}
+	}
+	return compare(column, "<>", value)
+}
+
+// Gt builds column > value.
+func Gt(column string, value interface{}) Condition {
+	return compare(column, ">", value)
+}
+
+// Gte builds column >= value.
+func Gte(column string, value interface{}) Condition {
+	return compare(column, ">=", value)
+}
+
+// Lt builds column < value.
+func Lt(column string, value interface{}) Condition {
+	return compare(column, "<", value)
+}
+
+// Lte builds column <= value.
+func Lte(column string, value interface{}) Condition {
+	return compare(column, "<=", value)
+}
+
+// Like builds column LIKE pattern.
+func Like(column string, pattern string) Condition {
+	return compare(column, "LIKE", pattern)
+}
+
+// Between builds column BETWEEN low AND high.
+func Between(column string, low, high interface{}) Condition {
+	return condition{column + " BETWEEN ? AND ?", []interface{}{low, high}}
+}
+
+// IsNull builds column IS NULL.
+func IsNull(column string) Condition {
+	return condition{column + " IS NULL", nil}
+}
+
+// In builds column IN (?, ?, ...) with one placeholder per element of the
+// slice values. An empty slice matches nothing.
+func In(column string, values interface{}) Condition {
+	vars := flatten(values)
+	if len(vars) == 0 {
+		return condition{"1 = 0", nil}
+	}
+	return condition{fmt.Sprintf("%s IN (%s)", column, BindVars(len(vars))), vars}
+}
+
+// NotIn builds column NOT IN (?, ?, ...), an empty slice matches everything.
+func NotIn(column string, values interface{}) Condition {
+	vars := flatten(values)
+	if len(vars) == 0 {
+		return condition{"1 = 1", nil}
+	}
+	return condition{fmt.Sprintf("%s NOT IN (%s)", column, BindVars(len(vars))), vars}
+}
+
+// And joins conditions by AND.
+func And(conds ...Condition) Condition {
+	return join(" AND ", conds)
+}
+
+// Or joins conditions by OR.
+func Or(conds ...Condition) Condition {
+	return join(" OR ", conds)
+}
+
+// Not negates the condition.
+func Not(cond Condition) Condition {
+	sql, vars := cond.Build()
+	return condition{fmt.Sprintf("NOT (%s)", sql), vars}
+}
+
+// join joins the conditions by sep, empty ones are skipped and joining none
+// builds an empty condition, which Where ignores.
+func join(sep string, conds []Condition) Condition {
+	var sqls []string
+	var vars []interface{}
+	for _, cond := range conds {
+		sql, v := cond.Build()
+		if sql == "" {
+			continue
+		}
+		sqls = append(sqls, sql)
+		vars = append(vars, v...)
+	}
+	if len(sqls) > 1 {
+		for i := range sqls {
+			sqls[i] = "(" + sqls[i] + ")"
+		}
+	}
+	return condition{strings.Join(sqls, sep), vars}
+}
+
+// Expand rewrites every ? of desc whose var is a slice into one placeholder
+// per element, e.g. ("Age IN (?)", [18, 25]) to ("Age IN (?, ?)", 18, 25).
+// Question marks inside quoted strings are left as they are.
+func Expand(desc string, vars []interface{}) (string, []interface{}) {
+	var sql strings.Builder
+	var expanded []interface{}
+	var quote rune
+	i := 0
+	for _, r := range desc {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?' && i < len(vars):
+			if isSlice(vars[i]) {
+				elems := flatten(vars[i])
+				if len(elems) == 0 {
+					sql.WriteString(emptyList(sql.String()))
+				}
+				sql.WriteString(BindVars(len(elems)))
+				expanded = append(expanded, elems...)
+			} else {
+				sql.WriteRune(r)
+				expanded = append(expanded, vars[i])
+			}
+			i++
+			continue
+		}
+		sql.WriteRune(r)
+	}
+	return sql.String(), append(expanded, vars[i:]...)
+}
+
+var notInRegexp = regexp.MustCompile(`(?i)\bNOT\s+IN\s*\(\s*$`)
+
+// emptyList returns what an empty slice is rendered to, given the SQL that
+// precedes it. IN () is a syntax error: IN (NULL) matches nothing, but
+// NOT IN (NULL) matches nothing either, so NOT IN gets an empty subquery
+// and matches everything, the same as NotIn.
+func emptyList(prefix string) string {
+	if notInRegexp.MatchString(prefix) {
+		return "SELECT NULL WHERE 1 = 0"
+	}
+	return "NULL"
+}
+
+// isSlice reports whether v is a slice or array to expand, []byte is a
+// single blob value.
+func isSlice(v interface{}) bool {
+	if _, ok := v.([]byte); ok || v == nil {
+		return false
+	}
+	kind := reflect.TypeOf(v).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+func flatten(values interface{}) []interface{} {
+	if !isSlice(values) {
+		return []interface{}{values}
+	}
+	v := reflect.ValueOf(values)
+	vars := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		vars = append(vars, v.Index(i).Interface())
+	}
+	return vars
+}
@@ -0,0 +1,46 @@
+package clause
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCondition(t *testing.T) {
+	cond := Or(
+		And(Eq("Name", "Tom"), In("Age", []int{18, 25})),
+		Not(Between("Age", 30, 40)),
+		IsNull("Name"),
+	)
+	sql, vars := cond.Build()
+	if sql != "((Name = ?) AND (Age IN (?, ?))) OR (NOT (Age BETWEEN ? AND ?)) OR (Name IS NULL)" {
+		t.Fatal("failed to build condition, got", sql)
+	}
+	if !reflect.DeepEqual(vars, []interface{}{"Tom", 18, 25, 30, 40}) {
+		t.Fatal("failed to build condition vars, got", vars)
+	}
+	if sql, _ := Or(And(), Eq("Name", "Tom")).Build(); sql != "Name = ?" {
+		t.Fatal("expect empty conditions to be skipped, got", sql)
+	}
+	if sql, _ := In("Age", []int{}).Build(); sql != "1 = 0" {
+		t.Fatal("expect empty IN to match nothing, got", sql)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	sql, vars := Expand("Name = '?' AND Age IN (?) AND Name <> ?", []interface{}{[]int{18, 25}, "Tom"})
+	if sql != "Name = '?' AND Age IN (?, ?) AND Name <> ?" {
+		t.Fatal("failed to expand slice var, got", sql)
+	}
+	if !reflect.DeepEqual(vars, []interface{}{18, 25, "Tom"}) {
+		t.Fatal("failed to expand vars, got", vars)
+	}
+	if _, vars := Expand("Data = ?", []interface{}{[]byte("x")}); len(vars) != 1 {
+		t.Fatal("expect []byte not to be expanded")
+	}
+	if sql, _ := Expand("Age IN (?)", []interface{}{[]int{}}); sql != "Age IN (NULL)" {
+		t.Fatal("failed to expand empty IN list, got", sql)
+	}
+	if sql, _ := Expand("Age not in ( ?)", []interface{}{[]int{}}); sql != "Age not in ( SELECT NULL WHERE 1 = 0)" {
+		t.Fatal("failed to expand empty NOT IN list, got", sql)
+	}
+}
@@ -0,0 +1,24 @@
+package clause
+
+// Expr is a SQL expression with its vars. The UPDATE and VALUES generators
+// render it inline instead of binding it as a var, so that
+// map[string]interface{}{"Stock": Expr{"Stock - ?", []interface{}{1}}}
+// builds SET Stock = Stock - ?. It can be used as a Condition as well.
+type Expr struct {
+	SQL  string
+	Vars []interface{}
+}
+
+// Build returns the SQL and vars of the expression.
+func (e Expr) Build() (string, []interface{}) {
+	return e.SQL, e.Vars
+}
+
+// bindValue returns the placeholder and vars of value, which is inlined if
+// it is an Expr.
+func bindValue(value interface{}) (string, []interface{}) {
+	if e, ok := value.(Expr); ok {
+		return e.SQL, e.Vars
+	}
+	return "?", []interface{}{value}
+}
@@ -2,6 +2,7 @@ package clause
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -16,15 +17,18 @@ func init() {
 	generators[VALUES] = _values
 	generators[SELECT] = _select
 	generators[LIMIT] = _limit
+	generators[OFFSET] = _offset
 	generators[WHERE] = _where
 	generators[ORDERBY] = _orderBy
 	generators[UPDATE] = _update
 	generators[DELETE] = _delete
 	generators[COUNT] = _count
+	generators[RETURNING] = _returning
+	generators[LOCKING] = _locking
 }
 
-// generate ?, ?, ?
-func genBindVars(num int) string {
+// BindVars returns num placeholders separated by commas, e.g. ?, ?, ?
+func BindVars(num int) string {
 	var vars []string
 	for i := 0; i < num; i++ {
 		vars = append(vars, "?")
@@ -43,20 +47,20 @@ func _insert(values ...interface{}) (string, []interface{}) {
 // e.g. [v1, v2, v3] => VALUES (?), (?), (?) v1, v2, v3
 func _values(values ...interface{}) (string, []interface{}) {
 	// VALUES ($v1), ($v2), ...
-	var bindStr string
 	var sql strings.Builder
 	var vars []interface{}
 	sql.WriteString("VALUES ")
 	for i, value := range values {
-		v := value.([]interface{})
-		if bindStr == "" {
-			bindStr = genBindVars(len(v))
+		var binds []string
+		for _, v := range value.([]interface{}) {
+			bind, bindVars := bindValue(v)
+			binds = append(binds, bind)
+			vars = append(vars, bindVars...)
 		}
-		sql.WriteString(fmt.Sprintf("(%v)", bindStr))
+		sql.WriteString(fmt.Sprintf("(%v)", strings.Join(binds, ", ")))
 		if i+1 != len(values) {
 			sql.WriteString(", ")
 		}
-		vars = append(vars, v...)
 	}
 	return sql.String(), vars
 }
@@ -74,6 +78,11 @@ func _limit(values ...interface{}) (string, []interface{}) {
 	return "LIMIT ?", values
 }
 
+func _offset(values ...interface{}) (string, []interface{}) {
+	// OFFSET $num
+	return "OFFSET ?", values
+}
+
 func _where(values ...interface{}) (string, []interface{}) {
 	// WHERE (desc) ()
 	desc, vars := values[0], values[1:]
@@ -90,13 +99,20 @@ func _update(values ...interface{}) (string, []interface{}) {
 	// output: UPDATE (table_name) SET (address = 'Texas';)
 	tableName := values[0]
 	m := values[1].(map[string]interface{})
-	var keys []string
+	// sort columns to build the same SQL for the same map
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sets []string
 	var vars []interface{}
-	for k, v := range m {
-		keys = append(keys, k+" = ?")
-		vars = append(vars, v)
+	for _, k := range keys {
+		bind, bindVars := bindValue(m[k])
+		sets = append(sets, k+" = "+bind)
+		vars = append(vars, bindVars...)
 	}
-	return fmt.Sprintf("UPDATE %s SET %s", tableName, strings.Join(keys, ", ")), vars
+	return fmt.Sprintf("UPDATE %s SET %s", tableName, strings.Join(sets, ", ")), vars
 }
 
 func _delete(values ...interface{}) (string, []interface{}) {
@@ -107,3 +123,17 @@ func _delete(values ...interface{}) (string, []interface{}) {
 func _count(values ...interface{}) (string, []interface{}) {
 	return _select(values[0], []string{"count(*)"})
 }
+
+func _returning(values ...interface{}) (string, []interface{}) {
+	// RETURNING (fields)
+	return fmt.Sprintf("RETURNING %s", strings.Join(values[0].([]string), ", ")), []interface{}{}
+}
+
+func _locking(values ...interface{}) (string, []interface{}) {
+	// FOR (strength) (options)
+	l := values[0].(Locking)
+	if l.Options == "" {
+		return fmt.Sprintf("FOR %s", l.Strength), []interface{}{}
+	}
+	return fmt.Sprintf("FOR %s %s", l.Strength, l.Options), []interface{}{}
+}
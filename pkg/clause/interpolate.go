@@ -0,0 +1,68 @@
+package clause
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Interpolate replaces every ? of sql outside quoted strings by the literal
+// of its var, e.g. ("Age > ?", 18) to Age > 18. It is meant to display
+// statements, not to run them: bind the vars instead.
+func Interpolate(sql string, vars []interface{}) string {
+	var out strings.Builder
+	var quote rune
+	i := 0
+	for _, r := range sql {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?' && i < len(vars):
+			out.WriteString(literal(vars[i]))
+			i++
+			continue
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// literal returns value as a SQL literal, strings being quoted and their
+// quotes doubled.
+func literal(value interface{}) string {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "?"
+		}
+		value = v
+	}
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return quoteString(v)
+	case []byte:
+		return "X'" + hex.EncodeToString(v) + "'"
+	case time.Time:
+		return quoteString(v.Format("2006-01-02 15:04:05.999999999-07:00"))
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	}
+	return quoteString(fmt.Sprint(value))
+}
+
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,17 @@
+package clause
+
+// Locking strengths and options
+const (
+	LockingStrengthUpdate    = "UPDATE"
+	LockingStrengthShare     = "SHARE"
+	LockingOptionsNoWait     = "NOWAIT"
+	LockingOptionsSkipLocked = "SKIP LOCKED"
+)
+
+// Locking is the row locking clause of SELECT, e.g.
+// Locking{Strength: LockingStrengthUpdate, Options: LockingOptionsSkipLocked}
+// builds FOR UPDATE SKIP LOCKED.
+type Locking struct {
+	Strength string
+	Options  string
+}
@@ -0,0 +1,178 @@
+package clause
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"go/ast"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IsNamedArg reports whether arg can bind the named parameters of a query,
+// that is a map[string]interface{} or a struct (pointer) other than
+// time.Time and driver.Valuer.
+func IsNamedArg(arg interface{}) bool {
+	if _, ok := arg.(map[string]interface{}); ok {
+		return true
+	}
+	switch arg.(type) {
+	case time.Time, *time.Time, driver.Valuer:
+		return false
+	}
+	v := reflect.Indirect(reflect.ValueOf(arg))
+	return v.Kind() == reflect.Struct
+}
+
+// HasNamed reports whether query contains a named parameter, @name or :name.
+func HasNamed(query string) bool {
+	found := false
+	scanNamed(query, func(string, string) string {
+		found = true
+		return ""
+	})
+	return found
+}
+
+// Named rewrites the named parameters @name and :name of query into ?
+// placeholders, binding them from arg, which is a map[string]interface{} or
+// a struct whose fields are looked up by name. A slice value expands to one
+// placeholder per element. It fails if a parameter has no value, or if a
+// key of the map is not used by the query. The placeholders are numbered by
+// Rebind once the statement is complete.
+func Named(query string, arg interface{}) (string, []interface{}, error) {
+	lookup, keys, err := namedLookup(arg)
+	if err != nil {
+		return "", nil, err
+	}
+	var vars []interface{}
+	used := make(map[string]bool)
+	sql := scanNamed(query, func(prefix, name string) string {
+		value, ok := lookup(name)
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("missing value of named parameter %q", name)
+			}
+			return ""
+		}
+		used[name] = true
+		elems := []interface{}{value}
+		if isSlice(value) {
+			elems = flatten(value)
+		}
+		if len(elems) == 0 {
+			return emptyList(prefix)
+		}
+		vars = append(vars, elems...)
+		return BindVars(len(elems))
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	for _, key := range keys {
+		if !used[key] {
+			return "", nil, fmt.Errorf("unused named parameter %q", key)
+		}
+	}
+	return sql, vars, nil
+}
+
+// namedLookup returns the value lookup of arg, and the keys that must be
+// used if arg is a map. Struct fields don't have to be used.
+func namedLookup(arg interface{}) (func(string) (interface{}, bool), []string, error) {
+	if m, ok := arg.(map[string]interface{}); ok {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return func(name string) (interface{}, bool) {
+			v, ok := m[name]
+			return v, ok
+		}, keys, nil
+	}
+	v := reflect.Indirect(reflect.ValueOf(arg))
+	if v.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("unsupported named argument %T", arg)
+	}
+	return func(name string) (interface{}, bool) {
+		if !ast.IsExported(name) {
+			return nil, false
+		}
+		f := v.FieldByName(name)
+		if !f.IsValid() {
+			return nil, false
+		}
+		return f.Interface(), true
+	}, nil, nil
+}
+
+// Rebind replaces the i-th ? of sql outside quoted strings by bindVar(i),
+// e.g. $1 for postgres. sql is returned as it is if bindVar(1) is ?.
+func Rebind(sql string, bindVar func(i int) string) string {
+	if bindVar(1) == "?" {
+		return sql
+	}
+	var out strings.Builder
+	var quote byte
+	n := 0
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			n++
+			out.WriteString(bindVar(n))
+			continue
+		}
+		out.WriteByte(c)
+	}
+	return out.String()
+}
+
+// scanNamed replaces every named parameter of query outside quoted strings
+// by the result of replace, which also gets the SQL written so far. The ::
+// of postgres casts is not a parameter.
+func scanNamed(query string, replace func(prefix, name string) string) string {
+	var sql strings.Builder
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			sql.WriteString("::")
+			i++
+			continue
+		case (c == '@' || c == ':') && i+1 < len(query) && isIdentStart(query[i+1]):
+			j := i + 1
+			for j < len(query) && isIdentPart(query[j]) {
+				j++
+			}
+			sql.WriteString(replace(sql.String(), query[i+1:j]))
+			i = j - 1
+			continue
+		}
+		sql.WriteByte(c)
+	}
+	return sql.String()
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || '0' <= c && c <= '9'
+}
@@ -0,0 +1,50 @@
+package clause
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNamed(t *testing.T) {
+	sql, vars, err := Named("Name = @name AND Age IN (:ages) AND Note <> ':x' AND Age::text <> @name",
+		map[string]interface{}{"name": "Tom", "ages": []int{18, 25}})
+	if err != nil || sql != "Name = ? AND Age IN (?, ?) AND Note <> ':x' AND Age::text <> ?" {
+		t.Fatal("failed to bind named parameters, got", sql, err)
+	}
+	if !reflect.DeepEqual(vars, []interface{}{"Tom", 18, 25, "Tom"}) {
+		t.Fatal("failed to bind named vars, got", vars)
+	}
+
+	type user struct {
+		Name string
+		Age  int
+	}
+	sql, vars, err = Named("Name = @Name", &user{"Sam", 25})
+	if err != nil || sql != "Name = ?" || !reflect.DeepEqual(vars, []interface{}{"Sam"}) {
+		t.Fatal("failed to bind struct fields, got", sql, vars, err)
+	}
+
+	sql, _, _ = Named("Age IN (@ages) AND Age NOT IN (@ages)", map[string]interface{}{"ages": []int{}})
+	if sql != "Age IN (NULL) AND Age NOT IN (SELECT NULL WHERE 1 = 0)" {
+		t.Fatal("failed to bind empty lists, got", sql)
+	}
+
+	if _, _, err := Named("Name = @name", map[string]interface{}{}); err == nil {
+		t.Fatal("expect error of missing parameter")
+	}
+	if _, _, err := Named("Name = @name", map[string]interface{}{"name": "Tom", "age": 1}); err == nil {
+		t.Fatal("expect error of unused parameter")
+	}
+}
+
+func TestRebind(t *testing.T) {
+	dollar := func(i int) string { return fmt.Sprintf("$%d", i) }
+	sql := Rebind("UPDATE User SET Age = ? WHERE Name = ? AND Note <> '?'", dollar)
+	if sql != "UPDATE User SET Age = $1 WHERE Name = $2 AND Note <> '?'" {
+		t.Fatal("failed to number placeholders, got", sql)
+	}
+	if sql := Rebind("Name = ?", func(int) string { return "?" }); sql != "Name = ?" {
+		t.Fatal("expect ? to be kept, got", sql)
+	}
+}
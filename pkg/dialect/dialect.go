@@ -1,6 +1,9 @@
 package dialect
 
-import "reflect"
+import (
+	"database/sql"
+	"reflect"
+)
 
 var dialectsMap = map[string]Dialect{}
 
@@ -9,6 +12,21 @@ var dialectsMap = map[string]Dialect{}
 type Dialect interface {
 	DataTypeOf(typ reflect.Value) string
 	TableExistSQL(tableName string) (string, []interface{})
+	// BindVar returns the positional placeholder of the i-th (from 1) var
+	BindVar(i int) string
+	// SupportReturning reports whether the database behind db supports
+	// INSERT/UPDATE/DELETE ... RETURNING, it may query db every time
+	SupportReturning(db *sql.DB) bool
+	// SupportLocking reports whether SELECT ... FOR UPDATE/SHARE is supported
+	SupportLocking() bool
+	// ExplainSQL returns the statement showing the query plan of sql
+	ExplainSQL(sql string) string
+	// IsUniqueViolation reports whether err is the violation of a unique
+	// or primary key constraint
+	IsUniqueViolation(err error) bool
+	// KeySQL returns the type of a column followed by its primary key and
+	// auto increment keywords, e.g. "integer PRIMARY KEY AUTOINCREMENT"
+	KeySQL(typ string, primaryKey, autoIncrement bool) string
 }
 
 // RegisterDialect regists dialect.
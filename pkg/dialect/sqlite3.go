@@ -1,8 +1,10 @@
 package dialect
 
 import (
+	"database/sql"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 )
 
@@ -41,3 +43,55 @@ func (s *sqlite3) TableExistSQL(tableName string) (string, []interface{}) {
 	args := []interface{}{tableName}
 	return "SELECT name FROM sqlite_master WHERE type='table' and name = ?", args
 }
+
+func (s *sqlite3) BindVar(i int) string {
+	return "?"
+}
+
+// SupportReturning checks the version of the SQLite library behind db,
+// RETURNING is supported since 3.35.0. The result is not cached, the
+// engine asks once.
+func (s *sqlite3) SupportReturning(db *sql.DB) bool {
+	var version string
+	if err := db.QueryRow("SELECT sqlite_version()").Scan(&version); err != nil {
+		return false
+	}
+	return returningSince(version)
+}
+
+// returningSince reports whether the SQLite version supports RETURNING.
+func returningSince(version string) bool {
+	var major, minor int
+	_, _ = fmt.Sscanf(version, "%d.%d", &major, &minor)
+	return major > 3 || major == 3 && minor >= 35
+}
+
+// SupportLocking is false as SQLite locks the whole database instead of rows,
+// a write transaction is exclusive anyway.
+func (s *sqlite3) SupportLocking() bool {
+	return false
+}
+
+// ExplainSQL uses EXPLAIN QUERY PLAN, whose rows are the id, parent, unused
+// and detail of the nodes of the plan tree.
+func (s *sqlite3) ExplainSQL(sql string) string {
+	return "EXPLAIN QUERY PLAN " + sql
+}
+
+// IsUniqueViolation matches the message of SQLite, the dialect doesn't
+// depend on a driver to check the error code.
+func (s *sqlite3) IsUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// KeySQL forces the type of an auto increment column to integer, SQLite
+// only allows AUTOINCREMENT on an INTEGER PRIMARY KEY, which bigint is not.
+func (s *sqlite3) KeySQL(typ string, primaryKey, autoIncrement bool) string {
+	if autoIncrement {
+		return "integer PRIMARY KEY AUTOINCREMENT"
+	}
+	if primaryKey {
+		return typ + " PRIMARY KEY"
+	}
+	return typ
+}
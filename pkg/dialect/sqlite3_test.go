@@ -0,0 +1,28 @@
+package dialect
+
+import "testing"
+
+func TestReturningSince(t *testing.T) {
+	for version, want := range map[string]bool{"3.34.1": false, "3.35.0": true, "3.45.2": true, "4.0": true, "": false} {
+		if got := returningSince(version); got != want {
+			t.Errorf("returningSince(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestSqlite3_KeySQL(t *testing.T) {
+	d := &sqlite3{}
+	for _, c := range []struct {
+		typ                       string
+		primaryKey, autoIncrement bool
+		want                      string
+	}{
+		{"text", false, false, "text"},
+		{"text", true, false, "text PRIMARY KEY"},
+		{"bigint", true, true, "integer PRIMARY KEY AUTOINCREMENT"},
+	} {
+		if got := d.KeySQL(c.typ, c.primaryKey, c.autoIncrement); got != c.want {
+			t.Errorf("KeySQL(%q, %v, %v) = %q, want %q", c.typ, c.primaryKey, c.autoIncrement, got, c.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
@@ -9,9 +10,15 @@ import (
 
 var (
 	errorLog = log.New(os.Stdout, "\033[31m[error]\033[0m ", log.LstdFlags|log.Lshortfile)
+	warnLog  = log.New(os.Stdout, "\033[33m[warn ]\033[0m ", log.LstdFlags|log.Lshortfile)
 	infoLog  = log.New(os.Stdout, "\033[34m[info ]\033[0m ", log.LstdFlags|log.Lshortfile)
-	loggers  = []*log.Logger{errorLog, infoLog}
+	debugLog = log.New(ioutil.Discard, "\033[36m[debug]\033[0m ", log.LstdFlags|log.Lshortfile)
+	loggers  = []*log.Logger{errorLog, warnLog, infoLog, debugLog}
 	mu       sync.Mutex
+
+	// slowLog 单独记录慢查询，调用位置由调用方给出
+	slowLog           = log.New(os.Stdout, "\033[33m[slow ]\033[0m ", log.LstdFlags)
+	slowOut io.Writer = os.Stdout
 )
 
 // log methods
@@ -22,12 +29,16 @@ var (
 	Info = infoLog.Println
 	// Infof print info log with format.
 	Infof = infoLog.Printf
+	// Slowf print slow query log with format.
+	Slowf = slowLog.Printf
 )
 
 // log levels
-// 越低越详细
+// 越低越详细，默认 InfoLevel
 const (
-	InfoLevel = iota
+	DebugLevel = iota - 1
+	InfoLevel
+	WarnLevel
 	ErrorLevel
 	Disabled
 )
@@ -44,7 +55,27 @@ func SetLevel(level int) {
 	if ErrorLevel < level {
 		errorLog.SetOutput(ioutil.Discard)
 	}
+	if WarnLevel < level {
+		warnLog.SetOutput(ioutil.Discard)
+	}
 	if InfoLevel < level {
 		infoLog.SetOutput(ioutil.Discard)
 	}
+	if DebugLevel < level {
+		debugLog.SetOutput(ioutil.Discard)
+	}
+	// 慢查询只在 Disabled 时关闭
+	if ErrorLevel < level {
+		slowLog.SetOutput(ioutil.Discard)
+	} else {
+		slowLog.SetOutput(slowOut)
+	}
+}
+
+// SetSlowOutput sends the slow query log to w, e.g. a file of its own.
+func SetSlowOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	slowOut = w
+	slowLog.SetOutput(w)
 }
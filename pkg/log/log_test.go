@@ -1,7 +1,9 @@
 package log
 
 import (
+	"bytes"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -15,3 +17,18 @@ func TestSetLevel(t *testing.T) {
 		t.Fatal("failed to set log level")
 	}
 }
+
+func TestSetSlowOutput(t *testing.T) {
+	var buf bytes.Buffer
+	SetSlowOutput(&buf)
+	defer SetSlowOutput(os.Stdout)
+	Slowf("%s", "SELECT 1")
+	SetLevel(Disabled)
+	Slowf("%s", "SELECT 2")
+	SetLevel(ErrorLevel)
+	Slowf("%s", "SELECT 3")
+	SetLevel(InfoLevel)
+	if out := buf.String(); !strings.Contains(out, "SELECT 1") || strings.Contains(out, "SELECT 2") || !strings.Contains(out, "SELECT 3") {
+		t.Fatal("failed to write slow log, got", out)
+	}
+}
@@ -0,0 +1,224 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Logger is the structured logger of the orm, set per engine with
+// session.WithLogger and overridden per session with Session.With. kv are
+// alternating keys and values, e.g. Info(ctx, "statement", "sql", sql).
+type Logger interface {
+	Debug(ctx context.Context, msg string, kv ...interface{})
+	Info(ctx context.Context, msg string, kv ...interface{})
+	Warn(ctx context.Context, msg string, kv ...interface{})
+	Error(ctx context.Context, msg string, kv ...interface{})
+}
+
+// SlowLogger is implemented by loggers writing slow queries apart, others
+// get them as warnings.
+type SlowLogger interface {
+	Slow(ctx context.Context, msg string, kv ...interface{})
+}
+
+// Leveler is implemented by the loggers whose level can be changed for a
+// single session, see session.Session.Debug.
+type Leveler interface {
+	// WithLevel returns a copy of the logger writing the entries of level
+	// and above, whatever the level the logger was built with.
+	WithLevel(level int) Logger
+}
+
+type valuesKey struct{}
+
+// WithValues returns a context whose key/values are added to the entries
+// logged with it, e.g. a request id.
+func WithValues(ctx context.Context, kv ...interface{}) context.Context {
+	return context.WithValue(ctx, valuesKey{}, append(ValuesFrom(ctx), kv...))
+}
+
+// ValuesFrom returns the key/values of ctx set by WithValues.
+func ValuesFrom(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+	kv, _ := ctx.Value(valuesKey{}).([]interface{})
+	return kv[:len(kv):len(kv)]
+}
+
+// pairs returns the key/values of ctx and kv as pairs, a value without key
+// gets the key !BADKEY.
+func pairs(ctx context.Context, kv []interface{}) [][2]interface{} {
+	all := append(ValuesFrom(ctx), kv...)
+	ps := make([][2]interface{}, 0, (len(all)+1)/2)
+	for i := 0; i < len(all); i += 2 {
+		if i+1 == len(all) {
+			ps = append(ps, [2]interface{}{"!BADKEY", all[i]})
+			break
+		}
+		ps = append(ps, [2]interface{}{all[i], all[i+1]})
+	}
+	return ps
+}
+
+// Default returns the colored text logger writing to the package loggers,
+// whose level is set by SetLevel. It is the logger of the sessions unless
+// set otherwise.
+func Default() Logger {
+	return colored{}
+}
+
+// colored writes to the package loggers, or to os.Stdout when its level is
+// forced by WithLevel.
+type colored struct {
+	forced bool
+	level  int
+}
+
+func text(ctx context.Context, msg string, kv []interface{}) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, p := range pairs(ctx, kv) {
+		fmt.Fprintf(&b, " %v=%v", p[0], p[1])
+	}
+	return b.String()
+}
+
+// forcedLogs maps the package loggers to the ones of the levels forced by
+// WithLevel, writing to os.Stdout. It is never changed once built.
+var forcedLogs = func() map[*log.Logger]*log.Logger {
+	m := make(map[*log.Logger]*log.Logger, len(loggers))
+	for _, l := range loggers {
+		// 不受 SetLevel 影响
+		m[l] = log.New(os.Stdout, l.Prefix(), l.Flags())
+	}
+	return m
+}()
+
+// output writes an entry of level to l, calldepth 3 reports the caller of
+// the colored methods.
+func (c colored) output(level int, l *log.Logger, s string) {
+	if c.forced {
+		if level < c.level {
+			return
+		}
+		l = forcedLogs[l]
+	}
+	_ = l.Output(3, s)
+}
+
+func (c colored) Debug(ctx context.Context, msg string, kv ...interface{}) {
+	c.output(DebugLevel, debugLog, text(ctx, msg, kv))
+}
+
+func (c colored) Info(ctx context.Context, msg string, kv ...interface{}) {
+	c.output(InfoLevel, infoLog, text(ctx, msg, kv))
+}
+
+func (c colored) Warn(ctx context.Context, msg string, kv ...interface{}) {
+	c.output(WarnLevel, warnLog, text(ctx, msg, kv))
+}
+
+func (c colored) Error(ctx context.Context, msg string, kv ...interface{}) {
+	c.output(ErrorLevel, errorLog, text(ctx, msg, kv))
+}
+
+// WithLevel implements Leveler, the entries go to os.Stdout.
+func (c colored) WithLevel(level int) Logger {
+	return colored{forced: true, level: level}
+}
+
+// Slow writes to the slow query log, see SetSlowOutput.
+func (colored) Slow(ctx context.Context, msg string, kv ...interface{}) {
+	_ = slowLog.Output(2, text(ctx, msg, kv))
+}
+
+// Discard is the silent logger.
+var Discard Logger = discard{}
+
+type discard struct{}
+
+func (discard) Debug(context.Context, string, ...interface{}) {}
+func (discard) Info(context.Context, string, ...interface{})  {}
+func (discard) Warn(context.Context, string, ...interface{})  {}
+func (discard) Error(context.Context, string, ...interface{}) {}
+
+// NewJSON returns a logger writing one JSON object per entry to w, with the
+// time, level and msg followed by the key/values. Entries below level,
+// e.g. InfoLevel, are dropped.
+func NewJSON(w io.Writer, level int) Logger {
+	return &jsonLogger{mu: &sync.Mutex{}, w: w, level: level}
+}
+
+type jsonLogger struct {
+	mu    *sync.Mutex // shared with the copies of WithLevel
+	w     io.Writer
+	level int
+}
+
+var levelNames = map[int]string{DebugLevel: "debug", InfoLevel: "info", WarnLevel: "warn", ErrorLevel: "error"}
+
+func (l *jsonLogger) log(ctx context.Context, level int, msg string, kv []interface{}) {
+	if level < l.level {
+		return
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	writeJSON(&b, "time", time.Now().Format(time.RFC3339Nano))
+	b.WriteByte(',')
+	writeJSON(&b, "level", levelNames[level])
+	b.WriteByte(',')
+	writeJSON(&b, "msg", msg)
+	for _, p := range pairs(ctx, kv) {
+		b.WriteByte(',')
+		writeJSON(&b, fmt.Sprint(p[0]), p[1])
+	}
+	b.WriteString("}\n")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = io.WriteString(l.w, b.String())
+}
+
+// writeJSON writes "key":value, errors and values that can't be marshaled
+// are written as strings.
+func writeJSON(b *strings.Builder, key string, value interface{}) {
+	k, _ := json.Marshal(key)
+	b.Write(k)
+	b.WriteByte(':')
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	b.Write(v)
+}
+
+// WithLevel implements Leveler.
+func (l *jsonLogger) WithLevel(level int) Logger {
+	return &jsonLogger{mu: l.mu, w: l.w, level: level}
+}
+
+func (l *jsonLogger) Debug(ctx context.Context, msg string, kv ...interface{}) {
+	l.log(ctx, DebugLevel, msg, kv)
+}
+
+func (l *jsonLogger) Info(ctx context.Context, msg string, kv ...interface{}) {
+	l.log(ctx, InfoLevel, msg, kv)
+}
+
+func (l *jsonLogger) Warn(ctx context.Context, msg string, kv ...interface{}) {
+	l.log(ctx, WarnLevel, msg, kv)
+}
+
+func (l *jsonLogger) Error(ctx context.Context, msg string, kv ...interface{}) {
+	l.log(ctx, ErrorLevel, msg, kv)
+}
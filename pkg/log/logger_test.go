@@ -0,0 +1,54 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNewJSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewJSON(&buf, InfoLevel)
+	ctx := WithValues(context.Background(), "request", 7)
+	l.Debug(ctx, "hidden")
+	l.Error(ctx, "statement failed", "sql", `SELECT "x"`, "error", errors.New("boom"), "alone")
+	out := buf.String()
+	if strings.Contains(out, "hidden") || strings.Count(out, "\n") != 1 {
+		t.Fatal("expect one entry, got", out)
+	}
+	if !strings.Contains(out, `"level":"error","msg":"statement failed","request":7,"sql":"SELECT \"x\"","error":"boom","!BADKEY":"alone"}`) {
+		t.Fatal("failed to log JSON, got", out)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	var buf bytes.Buffer
+	warnLog.SetOutput(&buf)
+	defer warnLog.SetOutput(os.Stdout)
+	Default().Warn(context.Background(), "row locking is ignored", "strength", "UPDATE")
+	if out := buf.String(); !strings.Contains(out, "logger_test.go") || !strings.Contains(out, "row locking is ignored strength=UPDATE") {
+		t.Fatal("failed to log colored text, got", out)
+	}
+	Discard.Error(context.Background(), "nothing")
+}
+
+func TestLeveler(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewJSON(&buf, ErrorLevel)
+	l.Info(context.Background(), "quiet")
+	l.(Leveler).WithLevel(DebugLevel).Debug(context.Background(), "loud")
+	if out := buf.String(); strings.Contains(out, "quiet") || !strings.Contains(out, `"level":"debug","msg":"loud"`) {
+		t.Fatal("failed to force the level, got", out)
+	}
+	if _, ok := Default().(Leveler); !ok {
+		t.Fatal("expect the default logger to be a Leveler")
+	}
+	for _, l := range loggers {
+		if forced := forcedLogs[l]; forced == nil || forced.Writer() != os.Stdout || forced.Prefix() != l.Prefix() {
+			t.Fatal("expect one forced logger per level writing to os.Stdout, got", forced)
+		}
+	}
+}
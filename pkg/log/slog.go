@@ -0,0 +1,76 @@
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// NewSlog returns a logger writing to l, the key/values of the context set
+// by WithValues come before the ones of the entry.
+func NewSlog(l *slog.Logger) Logger {
+	return slogLogger{l: l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+	// set by WithLevel, the entries of level and above skip the level of
+	// the handler
+	forced bool
+	level  slog.Level
+}
+
+var slogLevels = map[int]slog.Level{
+	DebugLevel: slog.LevelDebug,
+	InfoLevel:  slog.LevelInfo,
+	WarnLevel:  slog.LevelWarn,
+	ErrorLevel: slog.LevelError,
+	Disabled:   slog.LevelError + 1,
+}
+
+// WithLevel implements Leveler.
+func (s slogLogger) WithLevel(level int) Logger {
+	l, ok := slogLevels[level]
+	if !ok {
+		l = slog.LevelError + 1
+	}
+	return slogLogger{l: s.l, forced: true, level: l}
+}
+
+func (s slogLogger) log(ctx context.Context, level slog.Level, msg string, kv []interface{}) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	args := append(ValuesFrom(ctx), kv...)
+	if s.forced {
+		if level < s.level {
+			return
+		}
+		if !s.l.Enabled(ctx, level) {
+			r := slog.NewRecord(time.Now(), level, msg, 0)
+			r.Add(args...)
+			_ = s.l.Handler().Handle(ctx, r)
+			return
+		}
+	}
+	s.l.Log(ctx, level, msg, args...)
+}
+
+func (s slogLogger) Debug(ctx context.Context, msg string, kv ...interface{}) {
+	s.log(ctx, slog.LevelDebug, msg, kv)
+}
+
+func (s slogLogger) Info(ctx context.Context, msg string, kv ...interface{}) {
+	s.log(ctx, slog.LevelInfo, msg, kv)
+}
+
+func (s slogLogger) Warn(ctx context.Context, msg string, kv ...interface{}) {
+	s.log(ctx, slog.LevelWarn, msg, kv)
+}
+
+func (s slogLogger) Error(ctx context.Context, msg string, kv ...interface{}) {
+	s.log(ctx, slog.LevelError, msg, kv)
+}
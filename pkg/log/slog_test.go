@@ -0,0 +1,33 @@
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewSlog(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlog(slog.New(slog.NewTextHandler(&buf, nil)))
+	ctx := WithValues(context.Background(), "request", 7)
+	l.Debug(ctx, "hidden")
+	l.Warn(ctx, "slow query", "rows", 2)
+	if out := buf.String(); strings.Contains(out, "hidden") || !strings.Contains(out, `level=WARN msg="slow query" request=7 rows=2`) {
+		t.Fatal("failed to log through slog, got", out)
+	}
+}
+
+func TestSlogWithLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlog(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError})))
+	l.Info(context.Background(), "quiet")
+	l.(Leveler).WithLevel(InfoLevel).Info(context.Background(), "loud", "rows", 1)
+	if out := buf.String(); strings.Contains(out, "quiet") || !strings.Contains(out, `level=INFO msg=loud rows=1`) {
+		t.Fatal("failed to force the level, got", out)
+	}
+}
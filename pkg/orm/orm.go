@@ -1,9 +1,9 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
-	"strings"
+	"expvar"
 
 	"github.com/fusidic/orm/pkg/dialect"
 	"github.com/fusidic/orm/pkg/log"
@@ -12,44 +12,89 @@ import (
 
 // Engine is the entrance of user
 type Engine struct {
-	db      *sql.DB
-	dialect dialect.Dialect
+	db        *sql.DB
+	dialect   dialect.Dialect
+	opts      []session.Option
+	callbacks *session.Callbacks
+	metrics   *session.Metrics
+	logger    log.Logger
 }
 
-// NewEngine return a Engine
-func NewEngine(driver, source string) (e *Engine, err error) {
+// NewEngine return a Engine, opts are applied to every session it creates.
+// The engine logs with the logger of session.WithLogger if any.
+func NewEngine(driver, source string, opts ...session.Option) (e *Engine, err error) {
+	logger := session.LoggerOf(opts...)
+	ctx := context.Background()
 	db, err := sql.Open(driver, source)
 	if err != nil {
-		log.Error(err)
+		logger.Error(ctx, "failed to open database", "error", err)
 		return nil, err
 	}
 	// Send a ping to make sure the database connection is alive.
 	if err = db.Ping(); err != nil {
-		log.Error(err)
+		logger.Error(ctx, "failed to ping database", "error", err)
 		return nil, err
 	}
 	// make sure the specific dialect exists
 	dial, ok := dialect.GetDialect(driver)
 	if !ok {
-		log.Errorf("dialect %s Not Found", driver)
+		logger.Error(ctx, "dialect not found", "driver", driver)
 		return
 	}
-	e = &Engine{db: db, dialect: dial}
-	log.Info("Connect database success")
+	callbacks := session.NewCallbacks()
+	metrics := session.NewMetrics()
+	engineOpts := []session.Option{
+		session.WithCallbacks(callbacks),
+		session.WithMetrics(metrics),
+		session.WithReturningSupport(dial.SupportReturning(db)),
+	}
+	e = &Engine{
+		db:        db,
+		dialect:   dial,
+		opts:      append(engineOpts, opts...),
+		callbacks: callbacks,
+		metrics:   metrics,
+		logger:    logger,
+	}
+	logger.Info(ctx, "Connect database success")
 	return e, nil
 }
 
 // Close ...
 func (e *Engine) Close() {
 	if err := e.db.Close(); err != nil {
-		log.Error("Failed to close database")
+		e.logger.Error(context.Background(), "Failed to close database", "error", err)
+		return
 	}
-	log.Info("Close database success")
+	e.logger.Info(context.Background(), "Close database success")
+}
+
+// Callback returns the callback registry shared by all sessions of the
+// engine, e.g. to register a plugin before every query:
+// e.Callback().Query().Before("orm:query").Register("tenant", fn)
+func (e *Engine) Callback() *session.Callbacks {
+	return e.callbacks
+}
+
+// Metrics returns a snapshot of the metrics of the sessions of the engine,
+// with the stats of the connection pool.
+func (e *Engine) Metrics() session.MetricsSnapshot {
+	m := e.metrics.Snapshot()
+	m.DB = e.db.Stats()
+	return m
+}
+
+// PublishMetrics exports Metrics through expvar under name, it panics if
+// name is already published.
+func (e *Engine) PublishMetrics(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return e.Metrics()
+	}))
 }
 
 // NewSession encapsule session.New, returns a session.
 func (e *Engine) NewSession() *session.Session {
-	return session.New(e.db, e.dialect)
+	return session.New(e.db, e.dialect, e.opts...)
 }
 
 // TxFunc is the interface for transaction
@@ -59,7 +104,13 @@ type TxFunc func(*session.Session) (interface{}, error)
 
 // Transaction executes sql wrapped in a transaction, then automatically commit if no error occurs
 func (e *Engine) Transaction(f TxFunc) (result interface{}, err error) {
-	s := e.NewSession()
+	return e.TransactionContext(context.Background(), f)
+}
+
+// TransactionContext is Transaction with the context of the session passed
+// to f, the span of ctx is the parent of the span of the transaction.
+func (e *Engine) TransactionContext(ctx context.Context, f TxFunc) (result interface{}, err error) {
+	s := e.NewSession().WithContext(ctx)
 	if err := s.Begin(); err != nil {
 		return nil, err
 	}
@@ -69,12 +120,12 @@ func (e *Engine) Transaction(f TxFunc) (result interface{}, err error) {
 			panic(p) // re-throw panic after Rollback
 		} else if err != nil {
 			rollbackErr := s.Rollback() // err is non-nil; don't change it
-			log.Info(rollbackErr)
+			s.Logger().Info(ctx, "transaction rolled back", "cause", err, "error", rollbackErr)
 		} else {
 			defer func() {
 				if err != nil {
 					rollbackErr := s.Rollback()
-					log.Info(rollbackErr)
+					s.Logger().Info(ctx, "transaction rolled back", "cause", err, "error", rollbackErr)
 				}
 			}()
 			err = s.Commit() // err is nil; if Commit returns error update err
@@ -83,57 +134,10 @@ func (e *Engine) Transaction(f TxFunc) (result interface{}, err error) {
 	return f(s)
 }
 
-// difference returns a - b
-func difference(a []string, b []string) (diff []string) {
-	mapB := make(map[string]bool)
-	for _, v := range b {
-		mapB[v] = true
-	}
-	for _, v := range a {
-		if _, ok := mapB[v]; !ok {
-			diff = append(diff, v)
-		}
-	}
-	return
-}
-
-// Migrate table
+// Migrate table of value in a transaction, see session.Session.Migrate.
 func (e *Engine) Migrate(value interface{}) error {
-	_, err := e.Transaction(func(s *session.Session) (result interface{}, err error) {
-		if !s.Model(value).HasTable() {
-			log.Infof("table %s doesn't exist", s.GetRefTable().Name)
-			return nil, s.CreateTable()
-		}
-		// schema we set
-		table := s.GetRefTable()
-		// schema in database
-		rows, _ := s.Raw(fmt.Sprintf("SELECT * FROM %s LIMIT 1", table.Name)).QueryRows()
-		columns, _ := rows.Columns()
-		addCols := difference(table.FieldNames, columns)
-		delCols := difference(columns, table.FieldNames)
-		log.Infof("added cols %v, deleted cols %v", addCols, delCols)
-
-		// Loop: add column to table
-		for _, col := range addCols {
-			f := table.GetField(col)
-			sqlStr := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table.Name, f.Name, f.Type)
-			if _, err = s.Raw(sqlStr).Exec(); err != nil {
-				return
-			}
-		}
-
-		if len(delCols) == 0 {
-			return
-		}
-
-		// Migrate
-		tmp := "tmp_" + table.Name
-		fieldStr := strings.Join(table.FieldNames, ", ")
-		s.Raw(fmt.Sprintf("CREATE TABLE %s AS SELECT %s from %s;", tmp, fieldStr, table.Name))
-		s.Raw(fmt.Sprintf("DROP TABLE %s;", table.Name))
-		s.Raw(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", tmp, table.Name))
-		_, err = s.Exec()
-		return nil, err
+	_, err := e.Transaction(func(s *session.Session) (interface{}, error) {
+		return nil, s.Model(value).Migrate()
 	})
 	return err
 }
@@ -1,11 +1,15 @@
 package orm
 
 import (
+	"context"
 	"errors"
+	"expvar"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/fusidic/orm/pkg/session"
+	"github.com/fusidic/orm/pkg/trace"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -40,8 +44,8 @@ func Test_Engine_Transaction(t *testing.T) {
 func testTransactionRollback(t *testing.T) {
 	engine := OpenDB(t)
 	defer engine.Close()
-	s := engine.NewSession()
-	_ = s.Model(&User{}).DropTable()
+	s := engine.NewSession().Model(&User{})
+	_ = s.DropTable()
 	_, err := engine.Transaction(func(s *session.Session) (result interface{}, err error) {
 		_ = s.Model(&User{}).CreateTable()
 		_, err = s.Insert(&User{"Tom", 18})
@@ -55,8 +59,8 @@ func testTransactionRollback(t *testing.T) {
 func testTransactionCommit(t *testing.T) {
 	engine := OpenDB(t)
 	defer engine.Close()
-	s := engine.NewSession()
-	_ = s.Model(&User{}).DropTable()
+	s := engine.NewSession().Model(&User{})
+	_ = s.DropTable()
 	_, err := engine.Transaction(func(s *session.Session) (interface{}, error) {
 		_ = s.Model(&User{}).CreateTable()
 		_, err := s.Insert(&User{"Tom", 18})
@@ -85,3 +89,34 @@ func Test_Engine_Migrate(t *testing.T) {
 		t.Fatal("Failed to migrate table User, got columns", columns)
 	}
 }
+
+func Test_Engine_TransactionContext(t *testing.T) {
+	r := &trace.Recorder{}
+	engine, err := NewEngine("sqlite3", "../../orm.db", session.WithTracer(r))
+	if err != nil {
+		t.Fatal("failed to connect", err)
+	}
+	defer engine.Close()
+	ctx, root := r.Start(context.Background(), "request")
+	_, _ = engine.TransactionContext(ctx, func(s *session.Session) (interface{}, error) {
+		return s.Model(&User{}).Count()
+	})
+	root.End()
+	spans := r.Spans()
+	if len(spans) != 3 || spans[1].Parent != spans[0] || spans[2].Parent != spans[1] || !spans[1].Ended {
+		t.Fatal("failed to trace transaction", spans)
+	}
+}
+
+func Test_Engine_Metrics(t *testing.T) {
+	engine := OpenDB(t)
+	defer engine.Close()
+	_, _ = engine.NewSession().Raw("SELECT 1").Exec()
+	if m := engine.Metrics(); m.Queries != 1 || m.Operations["raw"].Count != 1 || m.DB.OpenConnections == 0 {
+		t.Fatalf("unexpected metrics %+v", m)
+	}
+	engine.PublishMetrics("orm_test")
+	if v := expvar.Get("orm_test"); v == nil || !strings.Contains(v.String(), `"Queries":1`) {
+		t.Fatal("failed to publish metrics", v)
+	}
+}
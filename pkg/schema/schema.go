@@ -1,6 +1,7 @@
 package schema
 
 import (
+	"fmt"
 	"go/ast"
 	"reflect"
 
@@ -10,17 +11,29 @@ import (
 // Schema is intended to deal with the convertion
 // between object & table:
 // type User struct {
-// 	Name string `orm:"PRIMARY KEY"`
-// 	Age  int
+// 	Name string `orm:"primaryKey;size:64"`
+// 	Age  int    `orm:"column:age;not null"`
 // }
 // to
-// CREATE TABLE `User` (`Name` text PRIMATY KEY, `Age` integer)
+// CREATE TABLE `User` (`Name` varchar(64) PRIMARY KEY, `age` integer NOT NULL)
 
 // Field represents a column of database.
 type Field struct {
-	Name string
-	Type string
-	Tag  string // 约束条件
+	Name          string   // Go 字段名
+	Column        string   // 列名，默认与字段名相同
+	Type          string   // 列类型，来自 type、size 或由 dialect 推断
+	Tag           string   // orm tag 原文
+	Size          int      // 字符串长度，来自 size
+	PrimaryKey    bool     // 主键
+	AutoIncrement bool     // 自增，Insert 时零值交给数据库生成
+	NotNull       bool     // 非空
+	Unique        bool     // 唯一
+	Default       string   // 默认值，原样写入 DDL
+	Index         string   // 索引名，"-" 表示默认名，空表示没有索引
+	Constraints   []string // tag 中其余的约束，原样写入 DDL
+	Rules         []*Rule  // 校验规则，来自 validate tag
+	Sensitive     bool     // 日志中隐去其值，来自 sensitive:"true" tag
+	typ           reflect.Type
 }
 
 // Schema represents a table of database.
@@ -28,16 +41,21 @@ type Schema struct {
 	Model      interface{}
 	Name       string
 	Fields     []*Field
-	FieldNames []string
+	FieldNames []string // column names of Fields, in order
+	PrimaryKey *Field   // nil if no field is tagged primaryKey
+	Hooks      uint     // bit set of the hooks implemented by Model, filled by session
+	Err        error    // first invalid tag, e.g. an unknown validate rule
 	fieldMap   map[string]*Field
 }
 
-// GetField ...
+// GetField returns the field of a column, or of a Go field name.
 func (schema *Schema) GetField(name string) *Field {
 	return schema.fieldMap[name]
 }
 
-// Parse converts any objects to Schema.
+// Parse converts any objects to Schema, see parseTag for the orm tag. An
+// invalid tag is kept in Err, sessions fail the statements of the model
+// with it.
 func Parse(object interface{}, d dialect.Dialect) *Schema {
 	modelType := reflect.Indirect(reflect.ValueOf(object)).Type()
 	schema := &Schema{
@@ -52,14 +70,44 @@ func Parse(object interface{}, d dialect.Dialect) *Schema {
 		if !p.Anonymous && ast.IsExported(p.Name) {
 			field := &Field{
 				Name: p.Name,
-				Type: d.DataTypeOf(reflect.Indirect(reflect.New(p.Type))),
+				typ:  p.Type,
 			}
 			if v, ok := p.Tag.Lookup("orm"); ok {
 				field.Tag = v
+				keep, err := parseTag(field, v)
+				if err != nil && schema.Err == nil {
+					schema.Err = fmt.Errorf("orm tag of %s.%s: %w", schema.Name, p.Name, err)
+				}
+				if !keep {
+					continue
+				}
+			}
+			if field.Column == "" {
+				field.Column = p.Name
+			}
+			if field.Type == "" {
+				field.Type = sizedType(p.Type, field.Size)
+			}
+			if field.Type == "" {
+				field.Type = d.DataTypeOf(reflect.Indirect(reflect.New(p.Type)))
+			}
+			if schema.PrimaryKey == nil && field.PrimaryKey {
+				schema.PrimaryKey = field
+			}
+			if v, ok := p.Tag.Lookup("validate"); ok {
+				rules, err := parseRules(v)
+				if err != nil && schema.Err == nil {
+					schema.Err = fmt.Errorf("validate tag of %s.%s: %w", schema.Name, p.Name, err)
+				}
+				field.Rules = rules
+			}
+			if v, ok := p.Tag.Lookup("sensitive"); ok {
+				field.Sensitive = v != "false"
 			}
 			schema.Fields = append(schema.Fields, field)
-			schema.FieldNames = append(schema.FieldNames, p.Name)
+			schema.FieldNames = append(schema.FieldNames, field.Column)
 			schema.fieldMap[p.Name] = field
+			schema.fieldMap[field.Column] = field
 		}
 	}
 	return schema
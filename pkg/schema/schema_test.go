@@ -21,4 +21,18 @@ func TestParse(t *testing.T) {
 	if schema.GetField("Name").Tag != "PRIMARY KEY" {
 		t.Fatal("failed to parse primary key")
 	}
+	if schema.PrimaryKey == nil || schema.PrimaryKey.Name != "Name" {
+		t.Fatal("failed to detect primary key field")
+	}
+}
+
+func TestParse_Sensitive(t *testing.T) {
+	type Credential struct {
+		User  string
+		Token string `sensitive:"true"`
+	}
+	schema := Parse(&Credential{}, TestDial)
+	if schema.GetField("User").Sensitive || !schema.GetField("Token").Sensitive {
+		t.Fatal("failed to parse sensitive fields")
+	}
 }
@@ -0,0 +1,133 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/fusidic/orm/pkg/dialect"
+)
+
+// parseTag sets the attributes of field from its orm tag, a list of entries
+// separated by semicolons, e.g.
+// `orm:"column:user_name;type:varchar(64);not null;default:'x';unique;index"`.
+// Keys are case insensitive and their spaces and underscores are ignored, so
+// the former `orm:"PRIMARY KEY"` still works. It reports false if the field
+// is ignored by "-", and the first invalid entry, e.g. size:abc. Other
+// entries are constraints copied into the DDL.
+func parseTag(field *Field, tag string) (keep bool, err error) {
+	for _, entry := range splitTag(tag) {
+		key, value := entry, ""
+		if i := strings.IndexByte(entry, ':'); i >= 0 {
+			key, value = entry[:i], strings.TrimSpace(entry[i+1:])
+		}
+		switch normalize(key) {
+		case "-":
+			return false, err
+		case "column":
+			field.Column = value
+		case "type":
+			field.Type = value
+		case "size":
+			if size, e := strconv.Atoi(value); e == nil && size > 0 {
+				field.Size = size
+			} else if err == nil {
+				err = fmt.Errorf("invalid size %q", value)
+			}
+		case "primarykey":
+			field.PrimaryKey = true
+		case "autoincrement":
+			field.AutoIncrement = true
+		case "notnull":
+			field.NotNull = true
+		case "unique":
+			field.Unique = true
+		case "default":
+			field.Default = value
+		case "index":
+			field.Index = value
+			if value == "" {
+				field.Index = "-" // named by the table and column
+			}
+		default:
+			field.Constraints = append(field.Constraints, entry)
+		}
+	}
+	return true, err
+}
+
+// splitTag splits tag on the semicolons outside single quotes.
+func splitTag(tag string) []string {
+	var entries []string
+	var quoted bool
+	start := 0
+	for i := 0; i <= len(tag); i++ {
+		if i < len(tag) && tag[i] == '\'' {
+			quoted = !quoted
+		}
+		if i == len(tag) || tag[i] == ';' && !quoted {
+			if entry := strings.TrimSpace(tag[start:i]); entry != "" {
+				entries = append(entries, entry)
+			}
+			start = i + 1
+		}
+	}
+	return entries
+}
+
+func normalize(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	return strings.NewReplacer(" ", "", "_", "").Replace(key)
+}
+
+// Definition returns the column definition of the field in CREATE TABLE,
+// e.g. "user_name varchar(64) NOT NULL DEFAULT 'x'". The primary key and
+// auto increment keywords are the ones of d.
+func (f *Field) Definition(d dialect.Dialect) string {
+	parts := []string{f.Column, d.KeySQL(f.Type, f.PrimaryKey, f.AutoIncrement)}
+	if f.NotNull {
+		parts = append(parts, "NOT NULL")
+	}
+	if f.Unique {
+		parts = append(parts, "UNIQUE")
+	}
+	if f.Default != "" {
+		parts = append(parts, "DEFAULT "+f.Default)
+	}
+	return strings.Join(append(parts, f.Constraints...), " ")
+}
+
+// IndexSQL returns the statements creating the indexes of the fields of
+// schema tagged index, an index is named idx_<table>_<column> unless the
+// tag gives a name, fields sharing a name share the index.
+func (schema *Schema) IndexSQL() []string {
+	var names []string
+	columns := make(map[string][]string)
+	for _, field := range schema.Fields {
+		if field.Index == "" {
+			continue
+		}
+		name := field.Index
+		if name == "-" {
+			name = fmt.Sprintf("idx_%s_%s", schema.Name, field.Column)
+		}
+		if _, ok := columns[name]; !ok {
+			names = append(names, name)
+		}
+		columns[name] = append(columns[name], field.Column)
+	}
+	sqls := make([]string, 0, len(names))
+	for _, name := range names {
+		sqls = append(sqls, fmt.Sprintf("CREATE INDEX %s ON %s (%s);", name, schema.Name, strings.Join(columns[name], ", ")))
+	}
+	return sqls
+}
+
+// sizedType returns the type of a string field with a size, "" otherwise.
+func sizedType(typ reflect.Type, size int) string {
+	if size > 0 && typ.Kind() == reflect.String {
+		return fmt.Sprintf("varchar(%d)", size)
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Member struct {
+	ID       int    `orm:"primaryKey;autoIncrement"`
+	Name     string `orm:"column:user_name;size:64;not null;default:'a;b';unique;index"`
+	Nickname string `orm:"type:varchar(32);index:idx_names"`
+	Alias    string `orm:"index:idx_names;CHECK (Alias <> '')"`
+	Internal string `orm:"-"`
+}
+
+func TestParse_Tag(t *testing.T) {
+	schema := Parse(&Member{}, TestDial)
+	if !reflect.DeepEqual(schema.FieldNames, []string{"ID", "user_name", "Nickname", "Alias"}) {
+		t.Fatal("failed to parse columns, got", schema.FieldNames)
+	}
+	if schema.PrimaryKey == nil || !schema.PrimaryKey.AutoIncrement {
+		t.Fatal("failed to parse primary key")
+	}
+	name := schema.GetField("Name")
+	if name == nil || name != schema.GetField("user_name") {
+		t.Fatal("expect the field to be found by name and by column")
+	}
+	defs := []string{
+		"ID integer PRIMARY KEY AUTOINCREMENT",
+		"user_name varchar(64) NOT NULL UNIQUE DEFAULT 'a;b'",
+		"Nickname varchar(32)",
+		"Alias text CHECK (Alias <> '')",
+	}
+	for i, field := range schema.Fields {
+		if got := field.Definition(TestDial); got != defs[i] {
+			t.Errorf("Definition() = %q, want %q", got, defs[i])
+		}
+	}
+	want := []string{
+		"CREATE INDEX idx_Member_user_name ON Member (user_name);",
+		"CREATE INDEX idx_names ON Member (Nickname, Alias);",
+	}
+	if got := schema.IndexSQL(); !reflect.DeepEqual(got, want) {
+		t.Fatal("failed to build indexes, got", got)
+	}
+}
+
+type Event struct {
+	ID int64 `orm:"primaryKey;autoIncrement"`
+}
+
+func TestParse_Int64AutoIncrement(t *testing.T) {
+	field := Parse(&Event{}, TestDial).GetField("ID")
+	if got := field.Definition(TestDial); got != "ID integer PRIMARY KEY AUTOINCREMENT" {
+		t.Fatal("expect an auto increment key to be an integer in SQLite, got", got)
+	}
+}
+
+func TestParse_InvalidSize(t *testing.T) {
+	type sized struct {
+		Name string `orm:"size:abc"`
+	}
+	schema := Parse(&sized{}, TestDial)
+	if schema.Err == nil || !strings.Contains(schema.Err.Error(), `invalid size "abc"`) {
+		t.Fatal("expect invalid size to be rejected, got", schema.Err)
+	}
+	if schema := Parse(&Member{}, TestDial); schema.Err != nil {
+		t.Fatal("expect valid tags, got", schema.Err)
+	}
+}
+
+func TestParse_LegacyTag(t *testing.T) {
+	field := Parse(&User{}, TestDial).GetField("Name")
+	if !field.PrimaryKey || field.Definition(TestDial) != "Name text PRIMARY KEY" {
+		t.Fatal("failed to parse the legacy tag, got", field.Definition(TestDial))
+	}
+}
@@ -0,0 +1,229 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rule is a validation rule of a field, read from its validate tag:
+//
+//	type User struct {
+//		Name  string `validate:"required,min=2,max=20"`
+//		Email string `validate:"email"`
+//		Role  string `validate:"oneof=admin guest"`
+//		Code  string `validate:"len=6,regex=^[0-9A-Z]+$"`
+//	}
+//
+// min, max and len compare the length of strings (in runes), slices and
+// maps, and the value of numbers. regex takes the rest of the tag, so it
+// must be the last rule.
+type Rule struct {
+	Name  string
+	Param string
+	check func(v reflect.Value) bool
+}
+
+// FieldError is a field failing a rule, or the error of a custom validation
+// if Err is set.
+type FieldError struct {
+	Field string
+	Rule  string
+	Param string
+	Value interface{}
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	if e.Err != nil {
+		if e.Field == "" {
+			return e.Err.Error()
+		}
+		return fmt.Sprintf("%s: %v", e.Field, e.Err)
+	}
+	rule := e.Rule
+	if e.Param != "" {
+		rule += "=" + e.Param
+	}
+	return fmt.Sprintf("%s: %v does not satisfy %s", e.Field, e.Value, rule)
+}
+
+// ValidationError lists every field of a record failing validation.
+type ValidationError struct {
+	Model  string
+	Fields []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Error())
+	}
+	return fmt.Sprintf("invalid %s: %s", e.Model, strings.Join(msgs, "; "))
+}
+
+// Validate checks every field of object against its rules, it returns nil
+// or a *ValidationError.
+func (schema *Schema) Validate(object interface{}) error {
+	objectValue := reflect.Indirect(reflect.ValueOf(object))
+	var errs []*FieldError
+	for _, field := range schema.Fields {
+		errs = append(errs, field.check(objectValue.FieldByName(field.Name))...)
+	}
+	return schema.validationError(errs)
+}
+
+// ValidateValues checks the values of a column to value map, as passed to
+// Update, against the rules of their fields. Numbers are converted to the
+// type of the field, unknown columns and values of other types, like SQL
+// expressions, are skipped.
+func (schema *Schema) ValidateValues(values map[string]interface{}) error {
+	var errs []*FieldError
+	for _, name := range schema.FieldNames {
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+		field := schema.fieldMap[name]
+		v := reflect.ValueOf(value)
+		switch {
+		case value == nil:
+			v = reflect.Zero(field.typ)
+		case v.Type() == field.typ:
+		case isNumber(v.Kind()) && isNumber(field.typ.Kind()):
+			v = v.Convert(field.typ)
+		default:
+			continue
+		}
+		errs = append(errs, field.check(v)...)
+	}
+	return schema.validationError(errs)
+}
+
+func (schema *Schema) validationError(errs []*FieldError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Model: schema.Name, Fields: errs}
+}
+
+func (field *Field) check(v reflect.Value) []*FieldError {
+	var errs []*FieldError
+	for _, rule := range field.Rules {
+		if !rule.check(v) {
+			errs = append(errs, &FieldError{
+				Field: field.Name,
+				Rule:  rule.Name,
+				Param: rule.Param,
+				Value: v.Interface(),
+			})
+		}
+	}
+	return errs
+}
+
+var emailRegexp = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+// parseRules parses a validate tag, it fails on an unknown or malformed
+// rule.
+func parseRules(tag string) ([]*Rule, error) {
+	var rules []*Rule
+	for tag != "" {
+		var item string
+		if strings.HasPrefix(tag, "regex=") {
+			item, tag = tag, ""
+		} else if i := strings.IndexByte(tag, ','); i >= 0 {
+			item, tag = tag[:i], tag[i+1:]
+		} else {
+			item, tag = tag, ""
+		}
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		rule := &Rule{Name: item}
+		if i := strings.IndexByte(item, '='); i >= 0 {
+			rule.Name, rule.Param = item[:i], item[i+1:]
+		}
+		check, err := newCheck(rule.Name, rule.Param)
+		if err != nil {
+			return nil, err
+		}
+		rule.check = check
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func newCheck(name, param string) (func(reflect.Value) bool, error) {
+	switch name {
+	case "required":
+		return func(v reflect.Value) bool { return !v.IsZero() }, nil
+	case "min", "max", "len":
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %s=%s: %w", name, param, err)
+		}
+		return func(v reflect.Value) bool {
+			size, ok := sizeOf(v)
+			switch {
+			case !ok:
+				return false
+			case name == "min":
+				return size >= n
+			case name == "max":
+				return size <= n
+			}
+			return size == n
+		}, nil
+	case "regex":
+		re, err := regexp.Compile(param)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule regex=%s: %w", param, err)
+		}
+		return func(v reflect.Value) bool {
+			return v.Kind() == reflect.String && re.MatchString(v.String())
+		}, nil
+	case "oneof":
+		allowed := strings.Fields(param)
+		return func(v reflect.Value) bool {
+			s := fmt.Sprint(v.Interface())
+			for _, a := range allowed {
+				if s == a {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case "email":
+		return func(v reflect.Value) bool {
+			return v.Kind() == reflect.String && emailRegexp.MatchString(v.String())
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown rule %s", name)
+}
+
+// sizeOf returns the length of strings, slices and maps, and the value of
+// numbers.
+func sizeOf(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func isNumber(kind reflect.Kind) bool {
+	return reflect.Int <= kind && kind <= reflect.Float64
+}
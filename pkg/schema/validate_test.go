@@ -0,0 +1,70 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Account struct {
+	Name  string `validate:"required,min=2,max=5"`
+	Email string `validate:"email"`
+	Role  string `validate:"oneof=admin guest"`
+	Code  string `validate:"len=3,regex=^[A-Z]{1,3}$"`
+	Age   int    `validate:"min=18"`
+	Tags  []byte `validate:"max=2"`
+}
+
+func TestSchema_Validate(t *testing.T) {
+	schema := Parse(&Account{}, TestDial)
+	if rules := schema.GetField("Code").Rules; len(rules) != 2 || rules[1].Param != "^[A-Z]{1,3}$" {
+		t.Fatal("failed to parse validate tag, got", rules)
+	}
+
+	err := schema.Validate(&Account{Name: "Tom", Email: "tom@example.com", Role: "admin", Code: "ABC", Age: 18})
+	if err != nil {
+		t.Fatal("expect record to be valid, got", err)
+	}
+
+	err = schema.Validate(&Account{Name: "Thomas", Email: "tom", Role: "root", Code: "ab", Age: 17, Tags: []byte("abc")})
+	var failed []string
+	for _, f := range err.(*ValidationError).Fields {
+		failed = append(failed, f.Field+":"+f.Rule)
+	}
+	expected := []string{"Name:max", "Email:email", "Role:oneof", "Code:len", "Code:regex", "Age:min", "Tags:max"}
+	if !reflect.DeepEqual(failed, expected) {
+		t.Fatal("failed to list every failing field, got", failed)
+	}
+	if !strings.Contains(err.Error(), "Name: Thomas does not satisfy max=5") {
+		t.Fatal("unexpected message", err)
+	}
+}
+
+func TestSchema_ValidateValues(t *testing.T) {
+	schema := Parse(&Account{}, TestDial)
+	if err := schema.ValidateValues(map[string]interface{}{"Age": int64(20), "Role": "guest", "Name": struct{}{}}); err != nil {
+		t.Fatal("expect values to be valid, got", err)
+	}
+	err := schema.ValidateValues(map[string]interface{}{"Age": 3, "Name": nil})
+	if err == nil || len(err.(*ValidationError).Fields) != 3 {
+		t.Fatal("expect Age and Name to fail, got", err)
+	}
+}
+
+func TestParse_InvalidRules(t *testing.T) {
+	type unknown struct {
+		Name string `validate:"required,unknown"`
+	}
+	type malformed struct {
+		Age int `validate:"min=x"`
+	}
+	if schema := Parse(&unknown{}, TestDial); schema.Err == nil || !strings.Contains(schema.Err.Error(), "unknown rule unknown") {
+		t.Fatal("expect unknown rule to be rejected, got", schema.Err)
+	}
+	if schema := Parse(&malformed{}, TestDial); schema.Err == nil {
+		t.Fatal("expect malformed rule to be rejected")
+	}
+	if schema := Parse(&Account{}, TestDial); schema.Err != nil {
+		t.Fatal("expect valid rules, got", schema.Err)
+	}
+}
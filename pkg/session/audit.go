@@ -0,0 +1,280 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/fusidic/orm/pkg/clause"
+	"github.com/fusidic/orm/pkg/schema"
+)
+
+// AuditLog is a row of the audit table, written for every record changed by
+// Insert, Update or Delete on a session with WithAudit. Create the table
+// with Migrate(&session.AuditLog{}).
+type AuditLog struct {
+	ID        int `orm:"primaryKey;autoIncrement"`
+	TableName string
+	RecordKey string // primary key of the record
+	Action    string // create, update or delete
+	Actor     string // set on the context by WithActor
+	Changes   string // JSON of the changed columns, see Diff
+	CreatedAt time.Time
+}
+
+// Change is the old and new value of a column, Old is nil for a record
+// inserted and New for one deleted. The values of sensitive fields are
+// Redacted.
+type Change struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// Diff returns the changed columns of the log.
+func (l *AuditLog) Diff() (map[string]Change, error) {
+	changes := make(map[string]Change)
+	err := json.Unmarshal([]byte(l.Changes), &changes)
+	return changes, err
+}
+
+// WithAudit makes Insert, Update and Delete write an AuditLog for every
+// record they change, in the transaction of the session, or in one begun
+// and committed around them. The rows changed are selected before and
+// after the statement, models without a primary key are not audited.
+func WithAudit() Option {
+	return func(s *Session) {
+		s.audit = true
+	}
+}
+
+type actorKey struct{}
+
+// WithActor returns a context whose changes are audited as made by actor,
+// e.g. the user of a request.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor of ctx set by WithActor.
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// History returns the audit logs of record, a model whose primary key is
+// set, oldest first.
+func (s *Session) History(record interface{}) ([]AuditLog, error) {
+	table := s.Model(record).GetRefTable()
+	if table.PrimaryKey == nil {
+		return nil, fmt.Errorf("table %s has no primary key to audit by", table.Name)
+	}
+	key := fmt.Sprint(reflect.Indirect(reflect.ValueOf(record)).FieldByName(table.PrimaryKey.Name).Interface())
+	h := s.clone()
+	h.Clear()
+	var logs []AuditLog
+	err := h.Model(&AuditLog{}).Where("TableName = ? AND RecordKey = ?", table.Name, key).OrderBy("ID").Find(&logs)
+	return logs, err
+}
+
+// audits reports whether the changes of s are audited.
+func (s *Session) audits() bool {
+	if !s.audit || s.dryRun != nil || s.refTable == nil || s.refTable.PrimaryKey == nil {
+		return false
+	}
+	_, ok := s.refTable.Model.(*AuditLog)
+	return !ok
+}
+
+// audited runs change, the Insert, Update or Delete of op, on a copy of s
+// which is not audited, and writes the audit logs of the records it
+// changes in the same transaction. records are the ones inserted, with the
+// keys the database has assigned once change has run, and values the ones
+// of Update. The records updated or deleted are selected, and locked if the
+// dialect locks rows, before the change.
+func (s *Session) audited(op Operation, records []interface{}, values map[string]interface{}, change func(s *Session) (int64, error)) (affected int64, err error) {
+	tx := s.clone()
+	tx.audit = false
+	var newKey interface{}
+	if op == OpUpdate {
+		if newKey, err = tx.updatedKey(values); err != nil {
+			return 0, err
+		}
+	}
+	if tx.tx == nil {
+		if err = tx.Begin(); err != nil {
+			return 0, err
+		}
+		defer func() {
+			if err != nil {
+				_ = tx.Rollback()
+				return
+			}
+			err = tx.Commit()
+		}()
+	}
+	var old [][]interface{}
+	if op != OpCreate {
+		q := tx.applyDefaultScope()
+		if q.dialect.SupportLocking() {
+			q = q.Locking(clause.Locking{Strength: "UPDATE"})
+		}
+		if old, err = q.auditRows(); err != nil {
+			return 0, err
+		}
+	}
+	if affected, err = change(tx); err != nil {
+		return affected, err
+	}
+	var logs [][]interface{}
+	switch op {
+	case OpCreate:
+		for _, record := range records {
+			table := tx.Model(record).GetRefTable()
+			logs = append(logs, tx.auditLog(table, op, nil, table.RecordValues(record)))
+		}
+	case OpUpdate:
+		table := tx.GetRefTable()
+		i := fieldIndex(table, table.PrimaryKey)
+		// the rows are selected again by their key, the new one if the
+		// update sets it
+		keyOf := func(values []interface{}) interface{} {
+			if newKey != nil {
+				return newKey
+			}
+			return values[i]
+		}
+		keys := make([]interface{}, len(old))
+		for j, values := range old {
+			keys[j] = keyOf(values)
+		}
+		updated := make(map[string][]interface{})
+		if len(keys) > 0 {
+			rows, err := tx.renew().Unscoped().Where(table.PrimaryKey.Column+" IN (?)", keys).auditRows()
+			if err != nil {
+				return affected, err
+			}
+			for _, values := range rows {
+				updated[fmt.Sprint(values[i])] = values
+			}
+		}
+		for _, values := range old {
+			key := fmt.Sprint(keyOf(values))
+			if _, ok := updated[key]; !ok {
+				return affected, fmt.Errorf("audit: updated row %s of %s not found", key, table.Name)
+			}
+			if log := tx.auditLog(table, op, values, updated[key]); log != nil {
+				logs = append(logs, log)
+			}
+		}
+	case OpDelete:
+		for _, values := range old {
+			logs = append(logs, tx.auditLog(tx.GetRefTable(), op, values, nil))
+		}
+	}
+	return affected, tx.writeAudit(logs)
+}
+
+// updatedKey returns the primary key set by the values of Update, nil if
+// they don't set it. A key set by an expression can't be audited as the
+// updated rows couldn't be found.
+func (s *Session) updatedKey(values map[string]interface{}) (interface{}, error) {
+	table := s.GetRefTable()
+	for name, value := range values {
+		if table.GetField(name) != table.PrimaryKey {
+			continue
+		}
+		if _, ok := value.(clause.Expr); ok {
+			return nil, fmt.Errorf("audit: primary key %s of %s can't be updated by an expression", name, table.Name)
+		}
+		return value, nil
+	}
+	return nil, nil
+}
+
+// renew returns a copy of s with its model and transaction but no clause.
+func (s *Session) renew() *Session {
+	s = s.clone()
+	s.Clear()
+	return s
+}
+
+// auditRows returns the values of the rows matched by the where clause of
+// s, in the order of the fields of the model. No hook is called.
+func (s *Session) auditRows() ([][]interface{}, error) {
+	records, err := s.selectRecords()
+	if err != nil {
+		return nil, err
+	}
+	table := s.GetRefTable()
+	rows := make([][]interface{}, len(records))
+	for i, record := range records {
+		rows[i] = table.RecordValues(record)
+	}
+	return rows, nil
+}
+
+func fieldIndex(table *schema.Schema, field *schema.Field) int {
+	for i, f := range table.Fields {
+		if f == field {
+			return i
+		}
+	}
+	return -1
+}
+
+// auditLog returns the values of the audit log of a record of table going
+// from old to values, nil if no column has changed.
+func (s *Session) auditLog(table *schema.Schema, op Operation, old, values []interface{}) []interface{} {
+	changes := make(map[string]Change)
+	for i, field := range table.Fields {
+		var c Change
+		if old != nil {
+			c.Old = old[i]
+		}
+		if values != nil {
+			c.New = values[i]
+		}
+		if old != nil && values != nil && reflect.DeepEqual(c.Old, c.New) {
+			continue
+		}
+		if field.Sensitive {
+			if old != nil {
+				c.Old = Redacted
+			}
+			if values != nil {
+				c.New = Redacted
+			}
+		}
+		changes[field.Column] = c
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	key := values
+	if key == nil {
+		key = old
+	}
+	diff, _ := json.Marshal(changes)
+	return []interface{}{table.Name, fmt.Sprint(key[fieldIndex(table, table.PrimaryKey)]),
+		op.String(), ActorFrom(s.Context()), string(diff), time.Now()}
+}
+
+// writeAudit inserts the audit logs, their ID is left to the database.
+func (s *Session) writeAudit(logs [][]interface{}) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	w := s.renew().Model(&AuditLog{})
+	table := w.GetRefTable()
+	values := make([]interface{}, len(logs))
+	for i, log := range logs {
+		values[i] = log
+	}
+	// ID is auto incremented
+	w.clause.Set(clause.INSERT, table.Name, table.FieldNames[1:])
+	w.clause.Set(clause.VALUES, values...)
+	stmt := w.run(OpCreate, modeExec, clause.INSERT, clause.VALUES)
+	return stmt.Err
+}
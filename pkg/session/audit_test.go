@@ -0,0 +1,104 @@
+package session
+
+import (
+	"context"
+	"testing"
+)
+
+type Wallet struct {
+	ID      int `orm:"PRIMARY KEY"`
+	Balance int
+	Pin     string `sensitive:"true"`
+}
+
+func TestSession_Audit(t *testing.T) {
+	ctx := WithActor(context.Background(), "alice")
+	s := NewSession().With(WithAudit()).WithContext(ctx)
+	for _, model := range []interface{}{&Wallet{}, &AuditLog{}} {
+		_ = s.Model(model).DropTable()
+		_ = s.Model(model).CreateTable()
+	}
+	if _, err := s.Insert(&Wallet{1, 100, "1234"}, &Wallet{2, 50, "0000"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Model(&Wallet{}).Where("Balance > ?", 60).Update("Balance", 80, "Pin", "4321"); err != nil {
+		t.Fatal(err)
+	}
+	// nothing changes, nothing is logged
+	if _, err := s.Model(&Wallet{}).Where("ID = ?", 2).Update("Balance", 50); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Model(&Wallet{}).Where("ID = ?", 1).Delete(); err != nil {
+		t.Fatal(err)
+	}
+
+	logs, err := s.History(&Wallet{ID: 1})
+	if err != nil || len(logs) != 3 {
+		t.Fatal("expect 3 audit logs of wallet 1, got", logs, err)
+	}
+	for i, action := range []string{"create", "update", "delete"} {
+		if logs[i].Action != action || logs[i].Actor != "alice" || logs[i].TableName != "Wallet" || logs[i].CreatedAt.IsZero() {
+			t.Fatalf("unexpected audit log %d: %+v", i, logs[i])
+		}
+	}
+	diff, err := logs[1].Diff()
+	if err != nil || len(diff) != 2 || diff["Balance"].Old != 100.0 || diff["Balance"].New != 80.0 ||
+		diff["Pin"].Old != Redacted || diff["Pin"].New != Redacted {
+		t.Fatal("unexpected update diff", diff, err)
+	}
+	diff, _ = logs[2].Diff()
+	if diff["Balance"].Old != 80.0 || diff["Balance"].New != nil {
+		t.Fatal("unexpected delete diff", diff)
+	}
+	if logs, _ = s.History(&Wallet{ID: 2}); len(logs) != 1 {
+		t.Fatal("expect only the insert of wallet 2 to be logged, got", logs)
+	}
+}
+
+func TestSession_AuditRollback(t *testing.T) {
+	s := NewSession().With(WithAudit())
+	for _, model := range []interface{}{&Wallet{}, &AuditLog{}} {
+		_ = s.Model(model).DropTable()
+		_ = s.Model(model).CreateTable()
+	}
+	_, _ = s.Insert(&Wallet{ID: 1})
+	// the duplicate key fails, the audit log of the first record is rolled back
+	if _, err := s.Insert(&Wallet{ID: 2}, &Wallet{ID: 1}); err == nil {
+		t.Fatal("expect a duplicate key error")
+	}
+	if logs, _ := s.History(&Wallet{ID: 2}); len(logs) != 0 {
+		t.Fatal("expect no audit log of a failed insert, got", logs)
+	}
+}
+
+func TestSession_AuditKeys(t *testing.T) {
+	s := NewSession().With(WithAudit())
+	for _, model := range []interface{}{&Profile{}, &AuditLog{}} {
+		_ = s.Model(model).DropTable()
+		_ = s.Model(model).CreateTable()
+	}
+	tom, sam := &Profile{Name: "Tom"}, &Profile{Name: "Sam"}
+	if _, err := s.Insert(tom, sam); err != nil {
+		t.Fatal(err)
+	}
+	if logs, err := s.History(sam); err != nil || len(logs) != 1 || logs[0].RecordKey != "2" {
+		t.Fatal("expect the insert to be logged with the generated key, got", logs, err)
+	}
+
+	if _, err := s.Model(&Profile{}).Where("ID = ?", 2).Update("ID", 7); err != nil {
+		t.Fatal(err)
+	}
+	logs, err := s.History(&Profile{ID: 7})
+	if err != nil || len(logs) != 1 || logs[0].Action != "update" {
+		t.Fatal("expect the update to be logged with the new key, got", logs, err)
+	}
+	if diff, _ := logs[0].Diff(); diff["ID"].Old != 2.0 || diff["ID"].New != 7.0 {
+		t.Fatal("unexpected key diff", diff)
+	}
+	if _, err := s.Model(&Profile{}).Where("ID = ?", 7).Update("ID", Expr("ID + 1")); err == nil {
+		t.Fatal("expect the update of the key by an expression to fail the audit")
+	}
+	if exists, _ := s.Model(&Profile{}).Where("ID = ?", 7).Exists(); !exists {
+		t.Fatal("expect the key not to be updated")
+	}
+}
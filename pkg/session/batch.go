@@ -0,0 +1,78 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/fusidic/orm/pkg/clause"
+)
+
+// FindInBatches walks through all eligible records batch by batch and calls
+// fn with every batch. Instead of OFFSET, batches are paged by the primary key
+// (keyset pagination), so the table must have one. values must be a pointer to
+// a slice, it holds the current batch when fn is called. Where conditions of
+// the session are kept, the iteration stops at the first error returned by fn.
+func (s *Session) FindInBatches(values interface{}, batchSize int, fn func(batch interface{}) error) error {
+	return s.findInBatches(values, batchSize, false, func(_ *Session, batch interface{}) error {
+		return fn(batch)
+	})
+}
+
+// FindInBatchesTx is like FindInBatches, but every batch is read and processed
+// in its own transaction. fn gets the session bound to that transaction, which
+// is committed if fn returns nil and rolled back otherwise.
+func (s *Session) FindInBatchesTx(values interface{}, batchSize int, fn func(tx *Session, batch interface{}) error) error {
+	return s.findInBatches(values, batchSize, true, fn)
+}
+
+func (s *Session) findInBatches(values interface{}, batchSize int, useTx bool, fn func(*Session, interface{}) error) error {
+	if batchSize <= 0 {
+		return errors.New("batch size must be positive")
+	}
+	destSlice := reflect.Indirect(reflect.ValueOf(values))
+	destType := destSlice.Type().Elem()
+	s = s.Model(reflect.New(destType).Elem().Interface())
+	table := s.GetRefTable()
+	if table.PrimaryKey == nil {
+		return fmt.Errorf("table %s has no primary key to page by", table.Name)
+	}
+	pk := table.PrimaryKey.Column
+
+	var last interface{}
+	for {
+		bs := s
+		if useTx {
+			bs = s.clone()
+			if err := bs.Begin(); err != nil {
+				return err
+			}
+		}
+		query := bs
+		if last != nil {
+			query = query.Where(clause.Gt(pk, last))
+		}
+		destSlice.Set(reflect.MakeSlice(destSlice.Type(), 0, batchSize))
+		err := query.OrderBy(pk + " ASC").Limit(batchSize).Find(values)
+		if err == nil && destSlice.Len() > 0 {
+			// fn gets a session without the conditions of the batch query
+			fresh := bs.clone()
+			fresh.Clear()
+			err = fn(fresh, values)
+		}
+		if useTx {
+			if err != nil {
+				_ = bs.Rollback()
+			} else {
+				err = bs.Commit()
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if destSlice.Len() < batchSize {
+			return nil
+		}
+		last = destSlice.Index(destSlice.Len() - 1).FieldByName(table.PrimaryKey.Name).Interface()
+	}
+}
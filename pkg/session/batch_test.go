@@ -0,0 +1,61 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+func testBatchInit(t *testing.T) *Session {
+	t.Helper()
+	s := NewSession().Model(&Account{})
+	err1 := s.DropTable()
+	err2 := s.CreateTable()
+	_, err3 := s.Insert(&Account{1, "a"}, &Account{2, "b"}, &Account{3, "c"}, &Account{4, "d"}, &Account{5, "e"})
+	if err1 != nil || err2 != nil || err3 != nil {
+		t.Fatal("failed init test records")
+	}
+	return s
+}
+
+func TestSession_FindInBatches(t *testing.T) {
+	s := testBatchInit(t)
+	var accounts []Account
+	var ids [][]int
+	err := s.Where("ID > ?", 1001).FindInBatches(&accounts, 2, func(batch interface{}) error {
+		var batchIDs []int
+		for _, a := range *batch.(*[]Account) {
+			batchIDs = append(batchIDs, a.ID)
+		}
+		ids = append(ids, batchIDs)
+		return nil
+	})
+	if err != nil || len(ids) != 2 || ids[0][0] != 1002 || ids[1][1] != 1005 {
+		t.Fatal("failed to find in batches, got", ids, err)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = s.FindInBatches(&accounts, 2, func(batch interface{}) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Fatal("failed to stop at callback error")
+	}
+}
+
+func TestSession_FindInBatchesTx(t *testing.T) {
+	s := testBatchInit(t)
+	var accounts []Account
+	err := s.FindInBatchesTx(&accounts, 3, func(tx *Session, batch interface{}) error {
+		_, err := tx.Model(&Account{}).Where("ID = ?", 1001).Delete()
+		if err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	count, _ := s.Model(&Account{}).Count()
+	if err == nil || count != 5 {
+		t.Fatal("failed to rollback batch transaction")
+	}
+}
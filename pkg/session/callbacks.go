@@ -0,0 +1,378 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fusidic/orm/pkg/clause"
+	"github.com/fusidic/orm/pkg/schema"
+	"github.com/fusidic/orm/pkg/trace"
+)
+
+// Operation is the kind of a statement, every kind has its own callbacks.
+type Operation int
+
+// Operations of statements
+const (
+	OpCreate Operation = iota
+	OpQuery
+	OpUpdate
+	OpDelete
+	OpRaw
+)
+
+var opNames = [...]string{"create", "query", "update", "delete", "raw"}
+
+func (op Operation) String() string {
+	return opNames[op]
+}
+
+type mode int
+
+const (
+	modeExec mode = iota
+	modeQuery
+	modeQueryRow
+)
+
+// Statement is the context of a statement passing through the callbacks.
+// A callback registered before the core one ("orm:create", "orm:query",
+// ...) may rewrite Clause, or SQL and Vars, and abort by setting Err. One
+// registered after it sees the Result or Rows, and Err.
+type Statement struct {
+	Session *Session
+	Context context.Context // of the session, holding the span of the statement
+	Op      Operation
+	Schema  *schema.Schema // nil for raw statements without model
+	Clause  *clause.Clause
+	Orders  []clause.Type // sub clauses SQL is built from, if SQL is empty
+	SQL     string        // with ? placeholders, numbered for the dialect when run
+	Vars    []interface{}
+	Result  sql.Result // of statements without returned rows
+	Rows    *sql.Rows  // of queries and statements with RETURNING
+	Row     *sql.Row   // of Session.QueryRow, nil if Err is set
+	Err     error
+	// Duration is the time spent running the statement, until the first
+	// row of a query is ready
+	Duration time.Duration
+	mode     mode
+
+	find        bool        // run by Find
+	cacheTables []string    // tables read by a query whose records may be cached
+	cacheKey    string      // key of the cached records, prefixed by their type
+	cached      interface{} // records found in the cache instead of Rows
+	generation  uint64      // of cacheTables before the query is run
+	span        trace.Span
+}
+
+// CallbackFunc is a function called with the statement.
+type CallbackFunc func(stmt *Statement)
+
+// Callbacks is the registry of the callbacks run for every statement of the
+// sessions sharing it, usually all sessions of an engine.
+type Callbacks struct {
+	mu         sync.RWMutex
+	processors [len(opNames)]*Processor
+}
+
+// NewCallbacks returns a registry holding only the core callbacks, which
+// build and run the statement.
+func NewCallbacks() *Callbacks {
+	c := &Callbacks{}
+	for op := range c.processors {
+		p := &Processor{callbacks: c}
+		_ = p.Register("orm:"+Operation(op).String(), execute)
+		c.processors[op] = p
+	}
+	return c
+}
+
+// defaultCallbacks is used by sessions without WithCallbacks, it is never
+// changed as it can't be reached from outside.
+var defaultCallbacks = NewCallbacks()
+
+// WithCallbacks sets the callback registry of the session.
+func WithCallbacks(c *Callbacks) Option {
+	return func(s *Session) {
+		s.callbacks = c
+	}
+}
+
+// Create returns the callbacks of Insert.
+func (c *Callbacks) Create() *Processor { return c.processors[OpCreate] }
+
+// Query returns the callbacks of Find, First, Count and other queries.
+func (c *Callbacks) Query() *Processor { return c.processors[OpQuery] }
+
+// Update returns the callbacks of Update.
+func (c *Callbacks) Update() *Processor { return c.processors[OpUpdate] }
+
+// Delete returns the callbacks of Delete.
+func (c *Callbacks) Delete() *Processor { return c.processors[OpDelete] }
+
+// Raw returns the callbacks of statements run by Raw, like CreateTable.
+func (c *Callbacks) Raw() *Processor { return c.processors[OpRaw] }
+
+// Processor holds the ordered callbacks of one operation.
+type Processor struct {
+	callbacks  *Callbacks
+	registered []*Callback
+	fns        []CallbackFunc
+}
+
+// Callback is a named callback with its order constraints.
+type Callback struct {
+	processor *Processor
+	name      string
+	before    string
+	after     string
+	fn        CallbackFunc
+}
+
+// Before starts registering a callback that runs before the named one.
+func (p *Processor) Before(name string) *Callback {
+	return &Callback{processor: p, before: name}
+}
+
+// After starts registering a callback that runs after the named one.
+func (p *Processor) After(name string) *Callback {
+	return &Callback{processor: p, after: name}
+}
+
+// Register adds a callback which runs after the one registered just before
+// it, unless ordered otherwise with Before or After.
+func (p *Processor) Register(name string, fn CallbackFunc) error {
+	return (&Callback{processor: p}).Register(name, fn)
+}
+
+// Before makes the callback run before the named one.
+func (c *Callback) Before(name string) *Callback {
+	c.before = name
+	return c
+}
+
+// After makes the callback run after the named one.
+func (c *Callback) After(name string) *Callback {
+	c.after = name
+	return c
+}
+
+// Register adds the callback under name, which must be unique.
+func (c *Callback) Register(name string, fn CallbackFunc) error {
+	p := c.processor
+	p.callbacks.mu.Lock()
+	defer p.callbacks.mu.Unlock()
+	if p.index(name) >= 0 {
+		return fmt.Errorf("callback %s is already registered", name)
+	}
+	c.name, c.fn = name, fn
+	return p.compile(append(p.registered[:len(p.registered):len(p.registered)], c))
+}
+
+// Replace replaces the function of the named callback, keeping its order.
+func (p *Processor) Replace(name string, fn CallbackFunc) error {
+	p.callbacks.mu.Lock()
+	defer p.callbacks.mu.Unlock()
+	i := p.index(name)
+	if i < 0 {
+		return fmt.Errorf("callback %s is not registered", name)
+	}
+	registered := append([]*Callback{}, p.registered...)
+	replaced := *registered[i]
+	replaced.fn = fn
+	registered[i] = &replaced
+	return p.compile(registered)
+}
+
+// Remove removes the named callback.
+func (p *Processor) Remove(name string) error {
+	p.callbacks.mu.Lock()
+	defer p.callbacks.mu.Unlock()
+	i := p.index(name)
+	if i < 0 {
+		return fmt.Errorf("callback %s is not registered", name)
+	}
+	registered := append(append([]*Callback{}, p.registered[:i]...), p.registered[i+1:]...)
+	return p.compile(registered)
+}
+
+func (p *Processor) index(name string) int {
+	for i, c := range p.registered {
+		if c.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// compile sorts the callbacks by their constraints, a callback without any
+// follows the one registered before it, and applies them if there is no
+// cycle. Constraints on callbacks not registered are ignored.
+func (p *Processor) compile(registered []*Callback) error {
+	n := len(registered)
+	index := make(map[string]int, n)
+	for i, c := range registered {
+		index[c.name] = i
+	}
+	// edges[i] lists the callbacks that must run after i
+	edges := make([][]int, n)
+	indegree := make([]int, n)
+	for i, c := range registered {
+		if j, ok := index[c.before]; ok && c.before != "" {
+			edges[i] = append(edges[i], j)
+			indegree[j]++
+		}
+		if j, ok := index[c.after]; ok && c.after != "" {
+			edges[j] = append(edges[j], i)
+			indegree[i]++
+		}
+		if c.before == "" && c.after == "" && i > 0 {
+			edges[i-1] = append(edges[i-1], i)
+			indegree[i]++
+		}
+	}
+	fns := make([]CallbackFunc, 0, n)
+	done := make([]bool, n)
+	for len(fns) < n {
+		next := -1
+		for i := range registered {
+			if !done[i] && indegree[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return fmt.Errorf("callbacks have a cyclic order")
+		}
+		done[next] = true
+		fns = append(fns, registered[next].fn)
+		for _, j := range edges[next] {
+			indegree[j]--
+		}
+	}
+	p.registered, p.fns = registered, fns
+	return nil
+}
+
+// run passes stmt through the callbacks in order.
+func (p *Processor) run(stmt *Statement) {
+	p.callbacks.mu.RLock()
+	fns := p.fns
+	p.callbacks.mu.RUnlock()
+	for _, fn := range fns {
+		fn(stmt)
+	}
+}
+
+// execute is the core callback, it builds the SQL from the clause if it is
+// not set, and runs it unless a callback has set Err.
+func execute(stmt *Statement) {
+	if stmt.SQL == "" && stmt.Clause != nil {
+		stmt.SQL, stmt.Vars = stmt.Clause.Build(stmt.Orders...)
+	}
+	s, ctx := stmt.Session, stmt.Context
+	if stmt.Err != nil {
+		s.logger.Error(ctx, "statement not run", "sql", stmt.SQL, "error", stmt.Err)
+		return
+	}
+	if s.dryRun != nil {
+		s.logger.Info(ctx, "dry run", s.sqlKV(stmt)...)
+		s.dryRun.add(stmt)
+		if stmt.mode == modeExec {
+			stmt.Result = dryRunResult{}
+		}
+		return
+	}
+	if stmt.cacheTables != nil {
+		stmt.cacheKey += cacheKey(stmt.SQL, stmt.Vars)
+		if cached, ok := s.queryCache.Get(stmt.cacheKey); ok {
+			s.logger.Info(ctx, "cached query", s.sqlKV(stmt)...)
+			stmt.cached = cached
+			return
+		}
+		stmt.generation = tableGenerations.get(stmt.cacheTables)
+	}
+	if stmt.Err = s.guardScan(stmt); stmt.Err != nil {
+		s.logger.Error(ctx, "statement not run", "sql", stmt.SQL, "error", stmt.Err)
+		return
+	}
+	// the placeholders are numbered once the statement is complete
+	query := clause.Rebind(stmt.SQL, s.dialect.BindVar)
+	start := time.Now()
+	if s.stmtCache != nil && cacheable(stmt.SQL) {
+		executePrepared(stmt, query)
+	} else {
+		executeDirect(stmt, query)
+		if s.stmtCache != nil && isDDL(stmt.SQL) {
+			s.stmtCache.Purge()
+		}
+	}
+	stmt.Duration = time.Since(start)
+	if s.metrics != nil {
+		s.metrics.observe(stmt)
+	}
+	s.logger.Info(ctx, "statement", append(s.sqlKV(stmt), "duration", stmt.Duration)...)
+	s.logSlow(stmt)
+	if stmt.Err != nil {
+		s.logger.Error(ctx, "statement failed", "sql", stmt.SQL, "error", stmt.Err)
+		return
+	}
+	if s.queryCache != nil && (stmt.Op != OpQuery && stmt.Op != OpRaw || stmt.mode == modeExec || !isRead(stmt.SQL)) {
+		s.invalidate(stmt)
+	}
+}
+
+// executeDirect runs query, the SQL of the statement for the dialect, in
+// the transaction of the session if it has begun one.
+func executeDirect(stmt *Statement, query string) {
+	ctx := stmt.Context
+	// Watch out it call conn() here.
+	db := stmt.Session.conn()
+	switch stmt.mode {
+	case modeExec:
+		stmt.Result, stmt.Err = db.ExecContext(ctx, query, stmt.Vars...)
+	case modeQuery:
+		stmt.Rows, stmt.Err = db.QueryContext(ctx, query, stmt.Vars...)
+	case modeQueryRow:
+		stmt.Row = db.QueryRowContext(ctx, query, stmt.Vars...)
+	}
+}
+
+// executePrepared runs query, the SQL of the statement for the dialect,
+// prepared by the cache of the session. Inside a transaction a statement
+// which isn't cached is run directly: preparing it on s.db would need
+// another connection than the one the transaction holds, and wait forever
+// if the pool has no other.
+func executePrepared(stmt *Statement, query string) {
+	s := stmt.Session
+	ctx := stmt.Context
+	var prepared *sql.Stmt
+	var release func()
+	var err error
+	if s.tx != nil {
+		var ok bool
+		if prepared, release, ok = s.stmtCache.lookup(query); !ok {
+			executeDirect(stmt, query)
+			return
+		}
+	} else if prepared, release, err = s.stmtCache.get(ctx, s.db, query); err != nil {
+		stmt.Err = err
+		return
+	}
+	// a statement closed while rows are open is released once they are
+	defer release()
+	if s.tx != nil {
+		prepared = s.tx.StmtContext(ctx, prepared)
+	}
+	switch stmt.mode {
+	case modeExec:
+		stmt.Result, stmt.Err = prepared.ExecContext(ctx, stmt.Vars...)
+	case modeQuery:
+		stmt.Rows, stmt.Err = prepared.QueryContext(ctx, stmt.Vars...)
+	case modeQueryRow:
+		stmt.Row = prepared.QueryRowContext(ctx, stmt.Vars...)
+	}
+}
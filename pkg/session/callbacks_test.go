@@ -0,0 +1,79 @@
+package session
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestProcessor_Order(t *testing.T) {
+	var order []string
+	record := func(name string) CallbackFunc {
+		return func(*Statement) { order = append(order, name) }
+	}
+	c := NewCallbacks()
+	p := c.Query()
+	_ = p.Replace("orm:query", record("orm:query"))
+	_ = p.Register("metrics", record("metrics"))
+	_ = p.Before("orm:query").Register("tenant", record("tenant"))
+	_ = p.After("tenant").Before("orm:query").Register("audit", record("audit"))
+	_ = p.Before("tenant").Register("auth", record("auth"))
+	p.run(&Statement{})
+	if !reflect.DeepEqual(order, []string{"auth", "tenant", "audit", "orm:query", "metrics"}) {
+		t.Fatal("failed to order callbacks, got", order)
+	}
+
+	if err := p.Register("metrics", record("metrics")); err == nil {
+		t.Fatal("expect duplicated callback to be rejected")
+	}
+	if err := p.Before("auth").After("metrics").Register("cycle", record("cycle")); err == nil {
+		t.Fatal("expect cyclic order to be rejected")
+	}
+	order = nil
+	_ = p.Remove("audit")
+	_ = p.Replace("metrics", record("stats"))
+	p.run(&Statement{})
+	if !reflect.DeepEqual(order, []string{"auth", "tenant", "orm:query", "stats"}) {
+		t.Fatal("failed to remove and replace callbacks, got", order)
+	}
+}
+
+func TestCallbacks_Plugin(t *testing.T) {
+	c := NewCallbacks()
+	// multi-tenancy: only see the users of age 25
+	_ = c.Query().Before("orm:query").Register("tenant", func(stmt *Statement) {
+		if stmt.Schema != nil && stmt.Schema.Name == "User" {
+			stmt.Clause.And("Age = ?", 25)
+		}
+	})
+	var statements []string
+	_ = c.Create().After("orm:create").Register("audit", func(stmt *Statement) {
+		if stmt.Err == nil {
+			n, _ := stmt.Result.RowsAffected()
+			statements = append(statements, strings.Fields(stmt.SQL)[0], stmt.Schema.Name, strconv.FormatInt(n, 10))
+		}
+	})
+	_ = c.Delete().Before("orm:delete").Register("readonly", func(stmt *Statement) {
+		stmt.Err = errors.New("read only")
+	})
+
+	s := New(TestDB, TestDial, WithCallbacks(c)).Model(&User{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(user1, user2, user3)
+	var users []User
+	if err := s.Find(&users); err != nil || len(users) != 2 {
+		t.Fatal("failed to rewrite clause in callback, got", users)
+	}
+	if !reflect.DeepEqual(statements, []string{"INSERT", "User", "3"}) {
+		t.Fatal("failed to inspect statement, got", statements)
+	}
+	if _, err := s.Delete(); err == nil || err.Error() != "read only" {
+		t.Fatal("expect callback to abort the statement, got", err)
+	}
+	if count, _ := NewSession().Model(&User{}).Count(); count != 3 {
+		t.Fatal("expect nothing to be deleted, got", count)
+	}
+}
@@ -0,0 +1,70 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/fusidic/orm/pkg/clause"
+)
+
+// ErrDryRun is returned by QueryRows, and by the Scan of QueryRow, in dry
+// run mode as there are no rows to read.
+var ErrDryRun = errors.New("statement not run in dry run mode")
+
+// dryRun records the statements of a session in dry run mode, it is shared
+// by the sessions derived from it.
+type dryRun struct {
+	mu    sync.Mutex
+	stmts []*Statement
+}
+
+func (d *dryRun) add(stmt *Statement) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stmts = append(d.stmts, stmt)
+}
+
+// dryRunResult is the result of a statement which isn't run.
+type dryRunResult struct{}
+
+func (dryRunResult) LastInsertId() (int64, error) { return 0, nil }
+func (dryRunResult) RowsAffected() (int64, error) { return 0, nil }
+
+// DryRun returns a session which builds the statements of Insert, Find,
+// Update, Delete, Count, CreateTable and others through the callbacks, but
+// records them instead of running them, see Statements. Nothing is found
+// nor affected: Find finds no record and First returns ErrRecordNotFound,
+// the After hooks are not called, and QueryRows and the Scan of QueryRow
+// return ErrDryRun, so does Migrate which reads the columns of the table.
+func (s *Session) DryRun() *Session {
+	s = s.clone()
+	s.dryRun = &dryRun{}
+	return s
+}
+
+// Statements returns the statements recorded by the session in dry run
+// mode, and by the sessions derived from it.
+func (s *Session) Statements() []*Statement {
+	if s.dryRun == nil {
+		return nil
+	}
+	s.dryRun.mu.Lock()
+	defer s.dryRun.mu.Unlock()
+	return append([]*Statement{}, s.dryRun.stmts...)
+}
+
+// ToSQL calls fn with a session in dry run mode, and returns the statements
+// it would run with their vars interpolated, separated by ";\n", e.g.
+// s.ToSQL(func(tx *Session) { _ = tx.Where("Age > ?", 18).Find(&users) })
+// returns SELECT Name, Age FROM User WHERE Age > 18. It is meant to be
+// displayed, the statements should be run with bound vars.
+func (s *Session) ToSQL(fn func(tx *Session)) string {
+	tx := s.DryRun()
+	fn(tx)
+	var sqls []string
+	for _, stmt := range tx.Statements() {
+		sqls = append(sqls, clause.Interpolate(strings.TrimSpace(stmt.SQL), stmt.Vars))
+	}
+	return strings.Join(sqls, ";\n")
+}
@@ -0,0 +1,87 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSession_DryRun(t *testing.T) {
+	s := NewSession().Model(&User{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+
+	dry := s.DryRun()
+	_ = dry.DropTable()
+	_ = dry.CreateTable()
+	_, _ = dry.Insert(user1)
+	var users []User
+	_ = dry.Where("Age > ?", 18).Limit(2).Find(&users)
+	_, _ = dry.Where("Name = ?", "Tom").Update("Age", 30)
+	count, _ := dry.Count()
+	_, _ = dry.Delete()
+	if count != 0 || len(users) != 0 {
+		t.Fatal("expect nothing to be found in dry run")
+	}
+
+	var sqls []string
+	for _, stmt := range dry.Statements() {
+		sqls = append(sqls, strings.Fields(stmt.SQL)[0])
+	}
+	if strings.Join(sqls, " ") != "DROP CREATE INSERT SELECT UPDATE SELECT DELETE" {
+		t.Fatal("failed to record statements, got", sqls)
+	}
+	if stmt := dry.Statements()[3]; stmt.SQL != "SELECT Name,Age FROM User WHERE Age > ? LIMIT ?" || len(stmt.Vars) != 2 {
+		t.Fatal("failed to build statement, got", stmt.SQL, stmt.Vars)
+	}
+	if len(s.Statements()) != 0 || !s.HasTable() {
+		t.Fatal("expect dry run not to touch the database")
+	}
+	if count, _ := s.Count(); count != 0 {
+		t.Fatal("expect no record to be inserted, got", count)
+	}
+}
+
+func TestSession_ToSQL(t *testing.T) {
+	s := NewSession().Model(&User{})
+	sql := s.ToSQL(func(tx *Session) {
+		var users []User
+		_ = tx.Where("Name = ?", "O'Neil").Find(&users)
+		_, _ = tx.Where("Age < ?", 18).Delete()
+	})
+	expected := "SELECT Name,Age FROM User WHERE Name = 'O''Neil';\nDELETE FROM User WHERE Age < 18"
+	if sql != expected {
+		t.Fatal("failed to render SQL, got", sql)
+	}
+}
+
+func TestSession_DryRunQueries(t *testing.T) {
+	s := NewSession().Model(&User{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(user1)
+
+	dry := s.DryRun()
+	var count int
+	if err := dry.Raw("SELECT count(*) FROM User").QueryRow().Scan(&count); !errors.Is(err, ErrDryRun) {
+		t.Fatal("expect QueryRow to fail with ErrDryRun, got", err)
+	}
+	if rows, err := dry.Raw("SELECT * FROM User").QueryRows(); rows != nil || !errors.Is(err, ErrDryRun) {
+		t.Fatal("expect QueryRows to fail with ErrDryRun, got", err)
+	}
+	var users []User
+	if err := dry.Find(&users); err != nil || len(users) != 0 {
+		t.Fatal("expect Find to find nothing, got", users, err)
+	}
+	if err := dry.First(&User{}); !errors.Is(err, ErrRecordNotFound) {
+		t.Fatal("expect First to find nothing, got", err)
+	}
+	if err := dry.Migrate(); !errors.Is(err, ErrDryRun) {
+		t.Fatal("expect Migrate to fail with ErrDryRun, got", err)
+	}
+	for _, stmt := range dry.Statements() {
+		if verb := strings.Fields(stmt.SQL)[0]; verb != "SELECT" {
+			t.Fatal("expect only queries to be recorded, got", stmt.SQL)
+		}
+	}
+}
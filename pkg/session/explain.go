@@ -0,0 +1,200 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/fusidic/orm/pkg/clause"
+)
+
+// ErrFullScan is returned by Find and Update under ScanGuardError if the
+// plan of the statement scans a table without index.
+var ErrFullScan = errors.New("full table scan")
+
+// PlanNode is a step of a query plan, e.g. SCAN User or SEARCH User USING
+// INDEX idx_age (Age>?).
+type PlanNode struct {
+	ID       int
+	Parent   int
+	Detail   string
+	Children []*PlanNode
+}
+
+// Plan is the query plan of a statement, as a tree of nodes.
+type Plan struct {
+	SQL   string
+	Vars  []interface{}
+	Nodes []*PlanNode // roots
+}
+
+// String returns the tree of the plan, one indented node per line.
+func (p *Plan) String() string {
+	var b strings.Builder
+	var walk func(nodes []*PlanNode, depth int)
+	walk = func(nodes []*PlanNode, depth int) {
+		for _, n := range nodes {
+			b.WriteString(strings.Repeat("  ", depth))
+			b.WriteString(n.Detail)
+			b.WriteByte('\n')
+			walk(n.Children, depth+1)
+		}
+	}
+	walk(p.Nodes, 0)
+	return b.String()
+}
+
+// FullScans returns the details of the nodes scanning a table without
+// index, SCAN User but neither SCAN User USING INDEX nor SEARCH User.
+func (p *Plan) FullScans() []string {
+	var scans []string
+	var walk func(nodes []*PlanNode)
+	walk = func(nodes []*PlanNode) {
+		for _, n := range nodes {
+			detail := strings.ToUpper(n.Detail)
+			if strings.HasPrefix(detail, "SCAN ") && !strings.Contains(detail, " USING ") &&
+				!strings.HasPrefix(detail, "SCAN CONSTANT") && !strings.HasPrefix(detail, "SCAN SUBQUERY") {
+				scans = append(scans, n.Detail)
+			}
+			walk(n.Children)
+		}
+	}
+	walk(p.Nodes)
+	return scans
+}
+
+// Explain returns the query plan of the statement the session would run,
+// its Raw SQL if any, otherwise the SELECT of Find with the conditions of
+// the chain. The statement is built in dry run mode, through the callbacks.
+func (s *Session) Explain() (*Plan, error) {
+	dry := s.DryRun()
+	switch {
+	case s.sql != "":
+		_, _ = dry.QueryRows()
+	case s.refTable == nil:
+		return nil, errors.New("neither Raw nor Model is set to explain")
+	default:
+		values := reflect.New(reflect.SliceOf(reflect.Indirect(reflect.ValueOf(s.GetRefTable().Model)).Type()))
+		if err := dry.Find(values.Interface()); err != nil {
+			return nil, err
+		}
+	}
+	stmts := dry.Statements()
+	if len(stmts) == 0 {
+		return nil, s.err
+	}
+	stmt := stmts[len(stmts)-1]
+	if stmt.Err != nil {
+		return nil, stmt.Err
+	}
+	return s.explain(stmt.SQL, stmt.Vars)
+}
+
+// explain runs the EXPLAIN of sql and parses the rows. The id, parent and
+// detail columns of SQLite make a tree, the rows of other formats are
+// roots whose detail joins all their columns.
+func (s *Session) explain(sql string, vars []interface{}) (*Plan, error) {
+	rows, err := s.conn().QueryContext(s.Context(), clause.Rebind(s.dialect.ExplainSQL(sql), s.dialect.BindVar), vars...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int, len(columns))
+	for i, c := range columns {
+		index[strings.ToLower(c)] = i
+	}
+	_, hasID := index["id"]
+	_, hasParent := index["parent"]
+	_, hasDetail := index["detail"]
+	tree := hasID && hasParent && hasDetail
+
+	plan := &Plan{SQL: sql, Vars: vars}
+	nodes := make(map[int]*PlanNode)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		node := &PlanNode{}
+		if tree {
+			_, _ = fmt.Sscan(fmt.Sprint(values[index["id"]]), &node.ID)
+			_, _ = fmt.Sscan(fmt.Sprint(values[index["parent"]]), &node.Parent)
+			node.Detail = text(values[index["detail"]])
+		} else {
+			details := make([]string, len(values))
+			for i, v := range values {
+				details[i] = text(v)
+			}
+			node.Detail = strings.Join(details, " ")
+		}
+		if parent, ok := nodes[node.Parent]; ok && tree {
+			parent.Children = append(parent.Children, node)
+		} else {
+			plan.Nodes = append(plan.Nodes, node)
+		}
+		if tree {
+			nodes[node.ID] = node
+		}
+	}
+	return plan, rows.Err()
+}
+
+func text(v interface{}) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// ScanGuard is what Find and Update do when the plan of their statement
+// scans a table without index, meant to catch missing indexes during
+// development as it runs an EXPLAIN before every statement.
+type ScanGuard int
+
+// Scan guards
+const (
+	ScanGuardOff ScanGuard = iota
+	// ScanGuardWarn logs the full scans.
+	ScanGuardWarn
+	// ScanGuardError fails with ErrFullScan instead of running the statement.
+	ScanGuardError
+)
+
+// WithScanGuard sets the scan guard of the session.
+func WithScanGuard(g ScanGuard) Option {
+	return func(s *Session) {
+		s.scanGuard = g
+	}
+}
+
+// guardScan explains the statement of Find or Update under the scan guard.
+// A statement that can't be explained is run anyway.
+func (s *Session) guardScan(stmt *Statement) error {
+	if s.scanGuard == ScanGuardOff || !stmt.find && stmt.Op != OpUpdate {
+		return nil
+	}
+	plan, err := s.explain(stmt.SQL, stmt.Vars)
+	if err != nil {
+		s.logger.Warn(stmt.Context, "failed to explain", "sql", stmt.SQL, "error", err)
+		return nil
+	}
+	scans := plan.FullScans()
+	if len(scans) == 0 {
+		return nil
+	}
+	err = fmt.Errorf("%w: %s in %s", ErrFullScan, strings.Join(scans, ", "), stmt.SQL)
+	if s.scanGuard == ScanGuardError {
+		return err
+	}
+	s.logger.Warn(stmt.Context, "full table scan", "scans", scans, "sql", stmt.SQL)
+	return nil
+}
@@ -0,0 +1,52 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSession_Explain(t *testing.T) {
+	s := NewSession().Model(&User{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+
+	plan, err := s.Where("Age = ?", 18).Explain()
+	if err != nil || len(plan.FullScans()) != 1 || !strings.Contains(plan.SQL, "WHERE Age = ?") {
+		t.Fatal("expect full scan of User", plan, err)
+	}
+	_, _ = s.Raw("CREATE INDEX idx_user_age ON User (Age)").Exec()
+	plan, err = s.Where("Age = ?", 18).Explain()
+	if err != nil || len(plan.FullScans()) != 0 || !strings.HasPrefix(plan.String(), "SEARCH") {
+		t.Fatal("expect search by index", plan, err)
+	}
+	plan, err = s.Raw("SELECT Age, COUNT(*) FROM User WHERE Name IN (SELECT Name FROM User WHERE Age > ?) GROUP BY Age", 18).Explain()
+	if err != nil || len(plan.Nodes) == 0 {
+		t.Fatal("failed to explain raw SQL", plan, err)
+	}
+	if _, err := NewSession().Explain(); err == nil {
+		t.Fatal("expect error without statement")
+	}
+}
+
+func TestSession_ScanGuard(t *testing.T) {
+	s := New(TestDB, TestDial, WithScanGuard(ScanGuardError)).Model(&User{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(user1, user2)
+
+	var users []User
+	if err := s.Where("Age = ?", 18).Find(&users); !errors.Is(err, ErrFullScan) {
+		t.Fatal("expect Find to be guarded, got", err)
+	}
+	if _, err := s.Where("Age = ?", 18).Update("Age", 20); !errors.Is(err, ErrFullScan) {
+		t.Fatal("expect Update to be guarded, got", err)
+	}
+	if count, err := s.Count(); err != nil || count != 2 {
+		t.Fatal("expect Count not to be guarded", count, err)
+	}
+	_, _ = s.Raw("CREATE INDEX idx_user_age ON User (Age)").Exec()
+	if err := s.Where("Age = ?", 18).Find(&users); err != nil || len(users) != 1 {
+		t.Fatal("failed to find by index", users, err)
+	}
+}
@@ -3,35 +3,234 @@ package session
 import (
 	"reflect"
 
-	"github.com/fusidic/orm/pkg/log"
+	"github.com/fusidic/orm/pkg/clause"
 )
 
+// Hook identifies one of the hook interfaces below.
+type Hook uint
+
 // Hooks constaints
 const (
-	BeforeQuery  = "BeforeQuery"
-	AfterQuery   = "AfterQuery"
-	BeforeUpdate = "BeforeUpdate"
-	AfterUpdate  = "AfterUpdate"
-	BeforeDelete = "BeforeDelete"
-	AfterDelete  = "AfterDelete"
-	BeforeInsert = "BeforeInsert"
-	AfterInsert  = "AfterInsert"
+	BeforeQuery Hook = 1 << iota
+	AfterQuery
+	BeforeUpdate
+	AfterUpdate
+	BeforeDelete
+	AfterDelete
+	BeforeInsert
+	AfterInsert
+)
+
+var hookNames = map[Hook]string{
+	BeforeQuery:  "BeforeQuery",
+	AfterQuery:   "AfterQuery",
+	BeforeUpdate: "BeforeUpdate",
+	AfterUpdate:  "AfterUpdate",
+	BeforeDelete: "BeforeDelete",
+	AfterDelete:  "AfterDelete",
+	BeforeInsert: "BeforeInsert",
+	AfterInsert:  "AfterInsert",
+}
+
+func (h Hook) String() string {
+	return hookNames[h]
+}
+
+// Hook interfaces, a model opts in by implementing them, which can be
+// checked at compile time with var _ session.BeforeInserter = (*User)(nil).
+type (
+	// BeforeQuerier is called on the model before Find.
+	BeforeQuerier interface{ BeforeQuery(s *Session) error }
+	// AfterQuerier is called on every record found.
+	AfterQuerier interface{ AfterQuery(s *Session) error }
+	// BeforeUpdater is called on every record matched before Update.
+	BeforeUpdater interface{ BeforeUpdate(s *Session) error }
+	// AfterUpdater is called on every record updated, or returned, after Update.
+	AfterUpdater interface{ AfterUpdate(s *Session) error }
+	// BeforeDeleter is called on every record matched before Delete.
+	BeforeDeleter interface{ BeforeDelete(s *Session) error }
+	// AfterDeleter is called on every record deleted, or returned, after Delete.
+	AfterDeleter interface{ AfterDelete(s *Session) error }
+	// BeforeInserter is called on every record before Insert.
+	BeforeInserter interface{ BeforeInsert(s *Session) error }
+	// AfterInserter is called on every record after Insert.
+	AfterInserter interface{ AfterInsert(s *Session) error }
 )
 
-// CallMethod calls the registered hooks
-func (s *Session) CallMethod(method string, value interface{}) {
-	// 通过 MethodByName 方法反射得到该队系那个的方法，method 即需获取的方法的方法名
-	fm := reflect.ValueOf(s.GetRefTable().Model).MethodByName(method)
-	if value != nil {
-		fm = reflect.ValueOf(value).MethodByName(method)
+// hooksOf returns the set of hooks implemented by the model or its pointer.
+func hooksOf(model interface{}) uint {
+	var hooks Hook
+	ptr := reflect.New(reflect.Indirect(reflect.ValueOf(model)).Type()).Interface()
+	if _, ok := ptr.(BeforeQuerier); ok {
+		hooks |= BeforeQuery
+	}
+	if _, ok := ptr.(AfterQuerier); ok {
+		hooks |= AfterQuery
+	}
+	if _, ok := ptr.(BeforeUpdater); ok {
+		hooks |= BeforeUpdate
+	}
+	if _, ok := ptr.(AfterUpdater); ok {
+		hooks |= AfterUpdate
+	}
+	if _, ok := ptr.(BeforeDeleter); ok {
+		hooks |= BeforeDelete
+	}
+	if _, ok := ptr.(AfterDeleter); ok {
+		hooks |= AfterDelete
+	}
+	if _, ok := ptr.(BeforeInserter); ok {
+		hooks |= BeforeInsert
+	}
+	if _, ok := ptr.(AfterInserter); ok {
+		hooks |= AfterInsert
+	}
+	return uint(hooks)
+}
+
+// CallMethod calls the hook of value, or of the model if value is nil, and
+// returns the error of the hook. The hooks implemented by the model are
+// cached in its schema, so that it costs nothing for models without hooks.
+func (s *Session) CallMethod(hook Hook, value interface{}) error {
+	if value == nil {
+		value = s.GetRefTable().Model
+	}
+	if table := s.refTable; table != nil && table.Hooks&uint(hook) == 0 &&
+		reflect.Indirect(reflect.ValueOf(value)).Type() == reflect.Indirect(reflect.ValueOf(table.Model)).Type() {
+		return nil
+	}
+	switch hook {
+	case BeforeQuery:
+		if h, ok := value.(BeforeQuerier); ok {
+			return h.BeforeQuery(s)
+		}
+	case AfterQuery:
+		if h, ok := value.(AfterQuerier); ok {
+			return h.AfterQuery(s)
+		}
+	case BeforeUpdate:
+		if h, ok := value.(BeforeUpdater); ok {
+			return h.BeforeUpdate(s)
+		}
+	case AfterUpdate:
+		if h, ok := value.(AfterUpdater); ok {
+			return h.AfterUpdate(s)
+		}
+	case BeforeDelete:
+		if h, ok := value.(BeforeDeleter); ok {
+			return h.BeforeDelete(s)
+		}
+	case AfterDelete:
+		if h, ok := value.(AfterDeleter); ok {
+			return h.AfterDelete(s)
+		}
+	case BeforeInsert:
+		if h, ok := value.(BeforeInserter); ok {
+			return h.BeforeInsert(s)
+		}
+	case AfterInsert:
+		if h, ok := value.(AfterInserter); ok {
+			return h.AfterInsert(s)
+		}
+	}
+	return nil
+}
+
+// callAfter calls the After hook of every record. The statement has already
+// been run, so if a hook fails, the error is returned for whoever began the
+// transaction, if any, to roll it back.
+func (s *Session) callAfter(hook Hook, records []interface{}) error {
+	if s.dryRun != nil {
+		return nil
+	}
+	for _, record := range records {
+		if err := s.CallMethod(hook, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// implements reports whether the model implements any of hooks.
+func (s *Session) implements(hooks ...Hook) bool {
+	for _, hook := range hooks {
+		if s.GetRefTable().Hooks&uint(hook) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// matchedRecords returns the records matched by the where clause of s for
+// the hooks of Update or Delete. In dry run nothing is selected, the hooks
+// get the model.
+func (s *Session) matchedRecords() ([]interface{}, error) {
+	if s.dryRun != nil {
+		return []interface{}{s.GetRefTable().Model}, nil
+	}
+	return s.selectRecords()
+}
+
+// selectRecords returns the records matched by the where clause of s, as
+// pointers to the model, locked if Locking is set. No hook is called.
+func (s *Session) selectRecords() (records []interface{}, err error) {
+	s = s.clone()
+	table := s.GetRefTable()
+	s.clause.Set(clause.SELECT, table.Name, table.FieldNames)
+	stmt := s.statement(OpQuery, modeQuery, clause.SELECT, clause.WHERE, clause.LOCKING)
+	s.runStatement(stmt, true)
+	if stmt.Err != nil {
+		return nil, stmt.Err
+	}
+	defer func() { stmt.endSpan(int64(len(records)), err) }()
+	modelType := reflect.Indirect(reflect.ValueOf(table.Model)).Type()
+	for stmt.Rows.Next() {
+		dest := reflect.New(modelType)
+		var values []interface{}
+		for _, field := range table.Fields {
+			values = append(values, dest.Elem().FieldByName(field.Name).Addr().Interface())
+		}
+		if err = stmt.Rows.Scan(values...); err != nil {
+			_ = stmt.Rows.Close()
+			return nil, err
+		}
+		records = append(records, dest.Interface())
+	}
+	if err = stmt.Rows.Close(); err != nil {
+		return nil, err
+	}
+	return records, stmt.Rows.Err()
+}
+
+// reload selects the records again by their primary keys once they are
+// updated. A record is kept as it was if the model has no primary key or
+// the update has changed it.
+func (s *Session) reload(records []interface{}) ([]interface{}, error) {
+	table := s.GetRefTable()
+	if table.PrimaryKey == nil || len(records) == 0 || s.dryRun != nil {
+		return records, nil
+	}
+	keyOf := func(record interface{}) interface{} {
+		return reflect.Indirect(reflect.ValueOf(record)).FieldByName(table.PrimaryKey.Name).Interface()
+	}
+	keys := make([]interface{}, len(records))
+	for i, record := range records {
+		keys[i] = keyOf(record)
+	}
+	updated, err := s.renew().Unscoped().Where(table.PrimaryKey.Column+" IN (?)", keys).selectRecords()
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[interface{}]interface{}, len(updated))
+	for _, record := range updated {
+		byKey[keyOf(record)] = record
 	}
-	param := []reflect.Value{reflect.ValueOf(s)}
-	if fm.IsValid() {
-		if v := fm.Call(param); len(v) > 0 {
-			if err, ok := v[0].Interface().(error); ok {
-				log.Error(err)
-			}
+	reloaded := make([]interface{}, len(records))
+	for i, record := range records {
+		if r, ok := byKey[keys[i]]; ok {
+			record = r
 		}
+		reloaded[i] = record
 	}
-	return
+	return reloaded, nil
 }
@@ -1,14 +1,18 @@
 package session
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/fusidic/orm/pkg/log"
 )
 
 type Account struct {
-	ID       int `orm:"PRIMARY KEY"`
-	Password string
+	ID       int    `orm:"PRIMARY KEY"`
+	Password string `sensitive:"true"`
 }
 
 func (a *Account) BeforeInsert(s *Session) error {
@@ -23,6 +27,21 @@ func (a *Account) AfterQuery(s *Session) error {
 	return nil
 }
 
+var (
+	_ BeforeInserter = (*Account)(nil)
+	_ AfterQuerier   = (*Account)(nil)
+)
+
+func TestHooksOf(t *testing.T) {
+	table := NewSession().Model(&Account{}).GetRefTable()
+	if table.Hooks != uint(BeforeInsert|AfterQuery) {
+		t.Fatal("failed to cache hooks of model, got", table.Hooks)
+	}
+	if table := NewSession().Model(&User{}).GetRefTable(); table.Hooks != 0 {
+		t.Fatal("expect no hooks, got", table.Hooks)
+	}
+}
+
 func TestSession_CallMethod(t *testing.T) {
 	s := NewSession().Model(&Account{})
 	_ = s.DropTable()
@@ -30,8 +49,130 @@ func TestSession_CallMethod(t *testing.T) {
 	_, _ = s.Insert(&Account{1, "123456"}, &Account{2, "qwerty"})
 
 	u := &Account{}
-	err := s.Find(u)
+	err := s.First(u)
 	if err != nil || u.ID != 1001 || u.Password != "******" {
 		t.Fatal("Failed to call hooks after query, got ", u)
 	}
 }
+
+var itemEvents []string
+
+type Item struct {
+	ID    int `orm:"PRIMARY KEY"`
+	Stock int
+}
+
+func (i *Item) BeforeInsert(s *Session) error {
+	if i.Stock < 0 {
+		return errors.New("negative stock")
+	}
+	return nil
+}
+
+func (i *Item) AfterInsert(s *Session) error {
+	itemEvents = append(itemEvents, fmt.Sprint("insert ", i.ID))
+	return nil
+}
+
+func (i *Item) BeforeUpdate(s *Session) error {
+	itemEvents = append(itemEvents, fmt.Sprint("before update ", i.ID, " ", i.Stock))
+	return nil
+}
+
+func (i *Item) AfterUpdate(s *Session) error {
+	itemEvents = append(itemEvents, fmt.Sprint("after update ", i.ID, " ", i.Stock))
+	return nil
+}
+
+func (i *Item) BeforeDelete(s *Session) error {
+	itemEvents = append(itemEvents, fmt.Sprint("before delete ", i.ID))
+	return nil
+}
+
+func (i *Item) AfterDelete(s *Session) error {
+	return errors.New("items can't be deleted")
+}
+
+func TestSession_HookLifecycle(t *testing.T) {
+	itemEvents = nil
+	s := NewSession().Model(&Item{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	if _, err := s.Insert(&Item{1, 10}, &Item{2, -1}); err == nil {
+		t.Fatal("expect BeforeInsert to stop the insert")
+	}
+	if count, _ := s.Count(); count != 0 {
+		t.Fatal("expect nothing to be inserted, got", count)
+	}
+	_, _ = s.Insert(&Item{1, 10}, &Item{2, 20})
+	_, _ = s.Where("ID = ?", 1).Decrement("Stock", 1)
+	expected := []string{"insert 1", "insert 2", "before update 1 10", "after update 1 9"}
+	if !reflect.DeepEqual(itemEvents, expected) {
+		t.Fatal("failed to call hooks, got", itemEvents)
+	}
+
+	itemEvents = nil
+	if err := s.Begin(); err != nil {
+		t.Fatal("failed to begin", err)
+	}
+	if _, err := s.Where("ID = ?", 2).Delete(); err == nil {
+		t.Fatal("expect AfterDelete to fail")
+	}
+	// the session which began the transaction rolls it back
+	if s.tx == nil {
+		t.Fatal("expect the transaction to be left to the caller")
+	}
+	if err := s.Rollback(); err != nil {
+		t.Fatal("failed to rollback", err)
+	}
+	if err := s.Rollback(); err != sql.ErrTxDone {
+		t.Fatal("expect the session to be out of the transaction, got", err)
+	}
+	if count, _ := s.Count(); count != 2 {
+		t.Fatal("expect delete to be rolled back, got", count)
+	}
+	if !reflect.DeepEqual(itemEvents, []string{"before delete 2"}) {
+		t.Fatal("failed to call BeforeDelete on the deleted records, got", itemEvents)
+	}
+}
+
+type Comment struct {
+	ID   int `orm:"primaryKey;autoIncrement"`
+	Body string
+}
+
+var commentCounts []int64
+
+// AfterInsert queries through the session it gets, which is in the
+// transaction of the insert.
+func (c *Comment) AfterInsert(s *Session) error {
+	count, err := s.Model(&Comment{}).Count()
+	if err != nil {
+		return err
+	}
+	commentCounts = append(commentCounts, count)
+	if c.Body == "spam" {
+		return errors.New("spam")
+	}
+	return nil
+}
+
+func TestSession_AfterInsertGeneratedKeys(t *testing.T) {
+	commentCounts = nil
+	s := NewSession().Model(&Comment{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	a, b := &Comment{Body: "a"}, &Comment{Body: "b"}
+	if _, err := s.Insert(a, b); err != nil || a.ID != 1 || b.ID != 2 {
+		t.Fatal("failed to insert records with generated keys, got", a, b, err)
+	}
+	if !reflect.DeepEqual(commentCounts, []int64{2, 2}) {
+		t.Fatal("expect AfterInsert to query in the transaction, got", commentCounts)
+	}
+	if _, err := s.Insert(&Comment{Body: "c"}, &Comment{Body: "spam"}); err == nil {
+		t.Fatal("expect AfterInsert to fail")
+	}
+	if count, _ := s.Count(); count != 2 {
+		t.Fatal("expect a failing AfterInsert to roll back the insert, got", count)
+	}
+}
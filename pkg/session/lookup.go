@@ -0,0 +1,104 @@
+package session
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/fusidic/orm/pkg/clause"
+)
+
+// FirstOrInit finds the first record matching attrs (column => value) and
+// the where clause. If there is none, value is filled with attrs instead,
+// without being saved.
+func (s *Session) FirstOrInit(value interface{}, attrs map[string]interface{}) error {
+	err := s.whereAttrs(attrs).First(value)
+	if err == ErrRecordNotFound {
+		return s.assignAttrs(value, attrs)
+	}
+	return err
+}
+
+// FirstOrCreate finds the first record matching attrs (column => value) and
+// the where clause, or inserts value filled with attrs if there is none.
+// Both run in one transaction, the session's if it has begun one, none in
+// dry run mode where nothing is run. The
+// select doesn't stop another caller from inserting the same record first:
+// if the insert then violates a unique constraint, it is rolled back to a
+// savepoint and the record inserted by the other caller is selected.
+func (s *Session) FirstOrCreate(value interface{}, attrs map[string]interface{}) (err error) {
+	if s.tx == nil && s.dryRun == nil {
+		s = s.clone()
+		if err = s.Begin(); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				_ = s.Rollback()
+			} else {
+				err = s.Commit()
+			}
+		}()
+	}
+	err = s.whereAttrs(attrs).First(value)
+	if err != ErrRecordNotFound {
+		return err
+	}
+	if err = s.assignAttrs(value, attrs); err != nil {
+		return err
+	}
+	if _, err = s.Raw("SAVEPOINT first_or_create").Exec(); err != nil {
+		return err
+	}
+	if _, err = s.Insert(value); !s.dialect.IsUniqueViolation(err) {
+		if err == nil {
+			_, err = s.Raw("RELEASE SAVEPOINT first_or_create").Exec()
+		}
+		return err
+	}
+	// inserted by another caller since the select
+	if _, err = s.Raw("ROLLBACK TO SAVEPOINT first_or_create").Exec(); err != nil {
+		return err
+	}
+	return s.whereAttrs(attrs).First(value)
+}
+
+// whereAttrs adds column = value conditions of attrs in column order.
+func (s *Session) whereAttrs(attrs map[string]interface{}) *Session {
+	columns := make([]string, 0, len(attrs))
+	for column := range attrs {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	conds := make([]clause.Condition, 0, len(columns))
+	for _, column := range columns {
+		conds = append(conds, clause.Eq(column, attrs[column]))
+	}
+	if len(conds) == 0 {
+		return s
+	}
+	return s.Where(clause.And(conds...))
+}
+
+// assignAttrs sets the fields of value whose columns are the keys of attrs.
+func (s *Session) assignAttrs(value interface{}, attrs map[string]interface{}) error {
+	table := s.Model(value).GetRefTable()
+	dest := reflect.Indirect(reflect.ValueOf(value))
+	for name, attr := range attrs {
+		f := table.GetField(name)
+		if f == nil {
+			return fmt.Errorf("%s has no column %s", table.Name, name)
+		}
+		field := dest.FieldByName(f.Name)
+		if attr == nil {
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+		v := reflect.ValueOf(attr)
+		if !v.Type().ConvertibleTo(field.Type()) {
+			return fmt.Errorf("cannot assign %T to field %s", attr, name)
+		}
+		field.Set(v.Convert(field.Type()))
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package session
+
+import "testing"
+
+func TestSession_FirstOrCreate(t *testing.T) {
+	s := testRecordInit(t)
+	u := &User{Age: 30}
+	if err := s.FirstOrCreate(u, map[string]interface{}{"Name": "Jack"}); err != nil || u.Name != "Jack" || u.Age != 30 {
+		t.Fatal("failed to create record, got", u, err)
+	}
+	u = &User{}
+	if err := s.FirstOrCreate(u, map[string]interface{}{"Name": "Tom"}); err != nil || u.Age != 18 {
+		t.Fatal("failed to find existing record, got", u, err)
+	}
+	if count, _ := s.Count(); count != 3 {
+		t.Fatal("expect 3 records, got", count)
+	}
+}
+
+func TestSession_FirstOrCreateDryRun(t *testing.T) {
+	s := testRecordInit(t)
+	metrics := NewMetrics()
+	dry := s.With(WithMetrics(metrics)).DryRun()
+	if err := dry.FirstOrCreate(&User{}, map[string]interface{}{"Name": "Jack"}); err != nil {
+		t.Fatal("failed to create record in dry run, got", err)
+	}
+	if begun := metrics.Snapshot().TxBegun; begun != 0 {
+		t.Fatal("expect no transaction in dry run, got", begun)
+	}
+	if stmts := dry.Statements(); len(stmts) != 4 {
+		t.Fatal("expect select, savepoint, insert and release to be recorded, got", stmts)
+	}
+	if count, _ := s.Count(); count != 2 {
+		t.Fatal("expect nothing to be inserted, got", count)
+	}
+}
+
+func TestSession_FirstOrCreateRenamed(t *testing.T) {
+	s := NewSession().Model(&Profile{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	p := &Profile{}
+	if err := s.FirstOrCreate(p, map[string]interface{}{"user_name": "Tom"}); err != nil || p.Name != "Tom" || p.ID != 1 {
+		t.Fatal("failed to create record by its renamed column, got", p, err)
+	}
+	p = &Profile{}
+	if err := s.FirstOrCreate(p, map[string]interface{}{"user_name": "Tom"}); err != nil || p.ID != 1 {
+		t.Fatal("failed to find record by its renamed column, got", p, err)
+	}
+	if err := s.FirstOrInit(&Profile{}, map[string]interface{}{"nothing": 1}); err == nil {
+		t.Fatal("expect an unknown column to fail")
+	}
+}
+
+func TestSession_FirstOrCreateRace(t *testing.T) {
+	s := testRecordInit(t)
+	callbacks := NewCallbacks()
+	raced := false
+	// another caller inserts Jack right after the select found nothing
+	_ = callbacks.Query().After("orm:query").Register("race", func(stmt *Statement) {
+		if !raced {
+			raced = true
+			_, _ = stmt.Session.Raw("INSERT INTO User (Name, Age) VALUES (?, ?)", "Jack", 40).Exec()
+		}
+	})
+	u := &User{Age: 30}
+	if err := s.With(WithCallbacks(callbacks)).FirstOrCreate(u, map[string]interface{}{"Name": "Jack"}); err != nil ||
+		u.Age != 40 {
+		t.Fatal("expect the record inserted by the other caller, got", u, err)
+	}
+	if count, _ := s.Count(); count != 3 {
+		t.Fatal("expect 3 records, got", count)
+	}
+}
+
+func TestSession_FirstOrInit(t *testing.T) {
+	s := testRecordInit(t)
+	u := &User{Age: 30}
+	if err := s.FirstOrInit(u, map[string]interface{}{"Name": "Jack"}); err != nil || u.Name != "Jack" {
+		t.Fatal("failed to init record, got", u, err)
+	}
+	if exists, err := s.Where("Name = ?", "Jack").Exists(); err != nil || exists {
+		t.Fatal("expect record not to be saved")
+	}
+	if exists, err := s.Where("Name = ?", "Tom").Exists(); err != nil || !exists {
+		t.Fatal("failed to check existence", err)
+	}
+	if err := s.Where("Name = ?", "Jack").First(&User{}); err != ErrRecordNotFound {
+		t.Fatal("expect ErrRecordNotFound, got", err)
+	}
+}
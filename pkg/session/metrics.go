@@ -0,0 +1,166 @@
+package session
+
+import (
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics counts the statements and transactions of the sessions it is set
+// on with WithMetrics, and keeps the histograms of the latency of the
+// statements per operation and per table. orm.NewEngine sets one on all
+// its sessions.
+type Metrics struct {
+	queries      uint64
+	errors       uint64
+	txBegun      uint64
+	txCommitted  uint64
+	txRolledBack uint64
+
+	mu         sync.RWMutex
+	operations map[string]*histogram
+	tables     map[string]*histogram
+}
+
+// MetricsSnapshot is the state of Metrics at some point.
+type MetricsSnapshot struct {
+	Queries      uint64 // statements run
+	Errors       uint64 // statements failed
+	TxBegun      uint64
+	TxCommitted  uint64
+	TxRolledBack uint64
+	// latency per operation (insert, find, update, delete, raw) and per
+	// table of the model
+	Operations map[string]HistogramSnapshot
+	Tables     map[string]HistogramSnapshot
+	DB         sql.DBStats // pool stats, filled by orm.Engine.Metrics
+}
+
+// HistogramSnapshot is a latency histogram, Buckets[i] counts the
+// statements taking at most LatencyBuckets[i], the last one all of them.
+type HistogramSnapshot struct {
+	Count   uint64
+	Sum     time.Duration
+	Buckets []uint64
+}
+
+// LatencyBuckets are the upper bounds of the buckets of the histograms,
+// followed by +Inf.
+var LatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+type histogram struct {
+	mu      sync.Mutex
+	count   uint64
+	sum     time.Duration
+	buckets []uint64 // not cumulative, the last one is +Inf
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := 0
+	for i < len(LatencyBuckets) && d > LatencyBuckets[i] {
+		i++
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.buckets == nil {
+		h.buckets = make([]uint64, len(LatencyBuckets)+1)
+	}
+	h.count++
+	h.sum += d
+	h.buckets[i]++
+}
+
+func (h *histogram) snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := HistogramSnapshot{Count: h.count, Sum: h.sum, Buckets: make([]uint64, len(h.buckets))}
+	var cumulative uint64
+	for i, n := range h.buckets {
+		cumulative += n
+		s.Buckets[i] = cumulative
+	}
+	return s
+}
+
+// NewMetrics returns empty metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{operations: make(map[string]*histogram), tables: make(map[string]*histogram)}
+}
+
+// WithMetrics makes the session count its statements and transactions in m.
+func WithMetrics(m *Metrics) Option {
+	return func(s *Session) {
+		s.metrics = m
+	}
+}
+
+// Snapshot returns the current state of the metrics.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	s := MetricsSnapshot{
+		Queries:      atomic.LoadUint64(&m.queries),
+		Errors:       atomic.LoadUint64(&m.errors),
+		TxBegun:      atomic.LoadUint64(&m.txBegun),
+		TxCommitted:  atomic.LoadUint64(&m.txCommitted),
+		TxRolledBack: atomic.LoadUint64(&m.txRolledBack),
+		Operations:   make(map[string]HistogramSnapshot),
+		Tables:       make(map[string]HistogramSnapshot),
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for op, h := range m.operations {
+		s.Operations[op] = h.snapshot()
+	}
+	for table, h := range m.tables {
+		s.Tables[table] = h.snapshot()
+	}
+	return s
+}
+
+// histogram returns the histogram of name in hs, created if missing.
+func (m *Metrics) histogram(hs map[string]*histogram, name string) *histogram {
+	m.mu.RLock()
+	h, ok := hs[name]
+	m.mu.RUnlock()
+	if ok {
+		return h
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if h, ok = hs[name]; !ok {
+		h = &histogram{}
+		hs[name] = h
+	}
+	return h
+}
+
+// metricNames are the labels of the operations in the metrics, the ones of
+// the methods running them.
+var metricNames = [len(opNames)]string{
+	OpCreate: "insert",
+	OpQuery:  "find",
+	OpUpdate: "update",
+	OpDelete: "delete",
+	OpRaw:    "raw",
+}
+
+// observe counts a statement which has been run.
+func (m *Metrics) observe(stmt *Statement) {
+	atomic.AddUint64(&m.queries, 1)
+	if stmt.Err != nil {
+		atomic.AddUint64(&m.errors, 1)
+	}
+	m.histogram(m.operations, metricNames[stmt.Op]).observe(stmt.Duration)
+	if stmt.Schema != nil {
+		m.histogram(m.tables, stmt.Schema.Name).observe(stmt.Duration)
+	}
+}
@@ -0,0 +1,51 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistogram(t *testing.T) {
+	h := &histogram{}
+	h.observe(500 * time.Microsecond)
+	h.observe(3 * time.Millisecond)
+	h.observe(time.Minute)
+	s := h.snapshot()
+	if s.Count != 3 || s.Sum != time.Minute+3500*time.Microsecond {
+		t.Fatalf("unexpected histogram %+v", s)
+	}
+	if len(s.Buckets) != len(LatencyBuckets)+1 || s.Buckets[0] != 1 || s.Buckets[1] != 2 || s.Buckets[len(s.Buckets)-2] != 2 || s.Buckets[len(s.Buckets)-1] != 3 {
+		t.Fatal("expect cumulative buckets, got", s.Buckets)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	s := New(TestDB, TestDial, WithMetrics(m)).Model(&User{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+
+	tx := s.clone()
+	_ = tx.Begin()
+	_, _ = tx.Insert(user1, user2)
+	_ = tx.Commit()
+	tx = s.clone()
+	_ = tx.Begin()
+	_, _ = tx.Delete()
+	_ = tx.Rollback()
+	var users []User
+	_ = s.Find(&users)
+	_, _ = s.Raw("SELECT * FROM Nothing").QueryRows()
+
+	snapshot := m.Snapshot()
+	if snapshot.Queries != 6 || snapshot.Errors != 1 {
+		t.Fatalf("unexpected counts %+v", snapshot)
+	}
+	if snapshot.TxBegun != 2 || snapshot.TxCommitted != 1 || snapshot.TxRolledBack != 1 {
+		t.Fatalf("unexpected transaction counts %+v", snapshot)
+	}
+	ops := snapshot.Operations
+	if ops["insert"].Count != 1 || ops["find"].Count != 1 || ops["delete"].Count != 1 || ops["raw"].Count != 3 || snapshot.Tables["User"].Count != 6 {
+		t.Fatalf("unexpected histograms %+v", snapshot)
+	}
+}
@@ -0,0 +1,72 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Migrate creates the table of the model if it doesn't exist, otherwise it
+// adds the columns of the new fields and drops the ones of the removed
+// fields by copying the table. It should be run in a transaction, and it
+// fails with ErrDryRun in dry run as it reads the columns of the table.
+func (s *Session) Migrate() error {
+	exists, err := s.hasTable()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		s.logger.Info(s.Context(), "table doesn't exist", "table", s.GetRefTable().Name)
+		return s.CreateTable()
+	}
+	// schema we set
+	table := s.GetRefTable()
+	// schema in database
+	rows, err := s.Raw(fmt.Sprintf("SELECT * FROM %s LIMIT 1", table.Name)).QueryRows()
+	if err != nil {
+		return err
+	}
+	columns, err := rows.Columns()
+	_ = rows.Close()
+	if err != nil {
+		return err
+	}
+	addCols := difference(table.FieldNames, columns)
+	delCols := difference(columns, table.FieldNames)
+	s.logger.Info(s.Context(), "migrate columns", "added", addCols, "deleted", delCols)
+
+	// Loop: add column to table
+	for _, col := range addCols {
+		f := table.GetField(col)
+		sqlStr := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table.Name, f.Column, f.Type)
+		if _, err := s.Raw(sqlStr).Exec(); err != nil {
+			return err
+		}
+	}
+
+	if len(delCols) == 0 {
+		return nil
+	}
+
+	// Migrate
+	tmp := "tmp_" + table.Name
+	fieldStr := strings.Join(table.FieldNames, ", ")
+	_, err = s.Raw(fmt.Sprintf("CREATE TABLE %s AS SELECT %s from %s;", tmp, fieldStr, table.Name)).
+		Raw(fmt.Sprintf("DROP TABLE %s;", table.Name)).
+		Raw(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", tmp, table.Name)).
+		Exec()
+	return err
+}
+
+// difference returns a - b
+func difference(a []string, b []string) (diff []string) {
+	mapB := make(map[string]bool)
+	for _, v := range b {
+		mapB[v] = true
+	}
+	for _, v := range a {
+		if _, ok := mapB[v]; !ok {
+			diff = append(diff, v)
+		}
+	}
+	return
+}
@@ -0,0 +1,25 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSession_Migrate(t *testing.T) {
+	s := NewSession()
+	_, _ = s.Raw("DROP TABLE IF EXISTS User;").Exec()
+	_, _ = s.Raw("CREATE TABLE User(Name text PRIMARY KEY, XXX integer);").Exec()
+	_, _ = s.Raw("INSERT INTO User(`Name`) values(?), (?)", "Tom", "Sam").Exec()
+	if err := s.Model(&User{}).Migrate(); err != nil {
+		t.Fatal("failed to migrate", err)
+	}
+
+	rows, err := s.Raw("SELECT * FROM User").QueryRows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rows.Close() }()
+	if columns, _ := rows.Columns(); !reflect.DeepEqual(columns, []string{"Name", "Age"}) {
+		t.Fatal("failed to migrate table User, got columns", columns)
+	}
+}
@@ -0,0 +1,186 @@
+package session
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/fusidic/orm/pkg/clause"
+)
+
+// ErrInvalidCursor is returned by Paginate if the cursor token is malformed
+// or has been tampered with.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// defaultCursorKey signs cursors of sessions without WithCursorKey, such
+// cursors are only valid in the current process.
+var defaultCursorKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// WithCursorKey sets the secret used to sign the cursors returned by
+// Paginate, so that they stay valid across processes.
+func WithCursorKey(key []byte) Option {
+	return func(s *Session) {
+		s.cursorKey = key
+	}
+}
+
+// Paginate finds a page of at most size records ordered by the OrderBy
+// columns (the primary key if OrderBy is not set), then by the primary key
+// unless one of them is unique, and returns the cursor of the next page,
+// which is empty on the last page. Passing the cursor back continues right
+// after the last record of the previous page, using a keyset condition
+// like WHERE (a, b) > (?, ?) instead of OFFSET.
+func (s *Session) Paginate(values interface{}, size int, cursor string) (next string, err error) {
+	if size <= 0 {
+		return "", errors.New("page size must be positive")
+	}
+	destSlice := reflect.Indirect(reflect.ValueOf(values))
+	destType := destSlice.Type().Elem()
+	s = s.Model(reflect.New(destType).Elem().Interface())
+	table := s.GetRefTable()
+
+	orderBy := s.orderBy
+	if orderBy == "" {
+		if table.PrimaryKey == nil {
+			return "", fmt.Errorf("table %s has no primary key to page by", table.Name)
+		}
+		orderBy = table.PrimaryKey.Column + " ASC"
+	}
+	columns, desc, err := parseOrderBy(orderBy)
+	if err != nil {
+		return "", err
+	}
+	unique := false
+	for _, col := range columns {
+		field := table.GetField(col)
+		if field == nil {
+			return "", fmt.Errorf("order by column %s is not a field of %s", col, table.Name)
+		}
+		unique = unique || field.PrimaryKey || field.Unique
+	}
+	// rows tying with the last one of a page would be skipped, the primary
+	// key breaks the ties
+	if !unique {
+		if table.PrimaryKey == nil {
+			return "", fmt.Errorf("order by %s is not unique and table %s has no primary key to page by", orderBy, table.Name)
+		}
+		direction := " ASC"
+		if desc {
+			direction = " DESC"
+		}
+		columns = append(columns, table.PrimaryKey.Column)
+		orderBy += ", " + table.PrimaryKey.Column + direction
+	}
+
+	if cursor != "" {
+		last, err := s.decodeCursor(cursor)
+		if err != nil {
+			return "", err
+		}
+		if len(last) != len(columns) {
+			return "", ErrInvalidCursor
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		s = s.Where(fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), op, clause.BindVars(len(columns))), last...)
+	}
+
+	// fetch one more record to know whether there is a next page
+	destSlice.Set(reflect.MakeSlice(destSlice.Type(), 0, size+1))
+	if err := s.OrderBy(orderBy).Limit(size + 1).Find(values); err != nil {
+		return "", err
+	}
+	if destSlice.Len() <= size {
+		return "", nil
+	}
+	destSlice.Set(destSlice.Slice(0, size))
+	lastRecord := destSlice.Index(size - 1)
+	last := make([]interface{}, 0, len(columns))
+	for _, col := range columns {
+		last = append(last, lastRecord.FieldByName(table.GetField(col).Name).Interface())
+	}
+	return s.encodeCursor(last)
+}
+
+// parseOrderBy splits "a ASC, b ASC" into its columns, keyset pagination
+// requires all of them to be sorted in the same direction.
+func parseOrderBy(orderBy string) (columns []string, desc bool, err error) {
+	for i, item := range strings.Split(orderBy, ",") {
+		parts := strings.Fields(item)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, false, fmt.Errorf("unsupported order by %q", orderBy)
+		}
+		d := len(parts) == 2 && strings.EqualFold(parts[1], "DESC")
+		if len(parts) == 2 && !d && !strings.EqualFold(parts[1], "ASC") {
+			return nil, false, fmt.Errorf("unsupported order by %q", orderBy)
+		}
+		if i > 0 && d != desc {
+			return nil, false, fmt.Errorf("mixed sort directions in %q", orderBy)
+		}
+		columns, desc = append(columns, parts[0]), d
+	}
+	return columns, desc, nil
+}
+
+// encodeCursor returns base64(json(values)).base64(hmac(json(values)))
+func (s *Session) encodeCursor(values []interface{}) (string, error) {
+	payload, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, s.cursorKey)
+	mac.Write(payload)
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (s *Session) decodeCursor(cursor string) ([]interface{}, error) {
+	parts := strings.Split(cursor, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+	enc := base64.RawURLEncoding
+	payload, err1 := enc.DecodeString(parts[0])
+	sum, err2 := enc.DecodeString(parts[1])
+	if err1 != nil || err2 != nil {
+		return nil, ErrInvalidCursor
+	}
+	mac := hmac.New(sha256.New, s.cursorKey)
+	mac.Write(payload)
+	if !hmac.Equal(sum, mac.Sum(nil)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var values []interface{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	// keep integers exact instead of decoding them as float64
+	for i, v := range values {
+		if n, ok := v.(json.Number); ok {
+			if i64, err := n.Int64(); err == nil {
+				values[i] = i64
+			} else {
+				values[i], _ = n.Float64()
+			}
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,72 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSession_Offset(t *testing.T) {
+	s := testBatchInit(t)
+	var accounts []Account
+	if err := s.OrderBy("ID DESC").Limit(2).Offset(1).Find(&accounts); err != nil ||
+		len(accounts) != 2 || accounts[0].ID != 1004 {
+		t.Fatal("failed to find with offset, got", accounts, err)
+	}
+}
+
+func TestSession_Paginate(t *testing.T) {
+	s := testBatchInit(t)
+	var accounts []Account
+	var ids []int
+	cursor := ""
+	for pages := 0; pages == 0 || cursor != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("failed to stop at last page")
+		}
+		var err error
+		cursor, err = s.OrderBy("ID DESC").Paginate(&accounts, 2, cursor)
+		if err != nil {
+			t.Fatal("failed to paginate", err)
+		}
+		for _, a := range accounts {
+			ids = append(ids, a.ID)
+		}
+	}
+	if len(ids) != 5 || ids[0] != 1005 || ids[4] != 1001 {
+		t.Fatal("failed to paginate, got", ids)
+	}
+
+	next, _ := s.Paginate(&accounts, 2, "")
+	if _, err := s.Paginate(&accounts, 2, next+"x"); err != ErrInvalidCursor {
+		t.Fatal("expect tampered cursor to be rejected")
+	}
+	other := New(TestDB, TestDial, WithCursorKey([]byte("secret")))
+	if _, err := other.Paginate(&accounts, 2, next); err != ErrInvalidCursor {
+		t.Fatal("expect cursor signed by another key to be rejected")
+	}
+}
+
+func TestSession_PaginateTies(t *testing.T) {
+	s := NewSession().Model(&User{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(&User{"A", 1}, &User{"B", 1}, &User{"C", 1}, &User{"D", 2})
+	var names []string
+	cursor := ""
+	for pages := 0; pages == 0 || cursor != ""; pages++ {
+		if pages > 2 {
+			t.Fatal("failed to stop at last page")
+		}
+		var users []User
+		var err error
+		if cursor, err = s.OrderBy("Age ASC").Paginate(&users, 2, cursor); err != nil {
+			t.Fatal("failed to paginate", err)
+		}
+		for _, u := range users {
+			names = append(names, u.Name)
+		}
+	}
+	if strings.Join(names, "") != "ABCD" {
+		t.Fatal("expect the primary key to break the ties of Age, got", names)
+	}
+}
@@ -0,0 +1,298 @@
+package session
+
+import (
+	"container/list"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// QueryCache stores the records found by Find, keyed by the final SQL and
+// vars of the query and tagged by the tables it reads. Implement it to
+// keep them in another store than memory.
+type QueryCache interface {
+	Get(key string) (value interface{}, ok bool)
+	Set(key string, value interface{}, tables []string)
+	// Invalidate removes the entries tagged by any of tables.
+	Invalidate(tables ...string)
+}
+
+// WithQueryCache makes Find look up its records in c before querying the
+// database, unless the session is inside a transaction. Insert, Update,
+// Delete, Exec and the queries other than SELECT through the sessions
+// invalidate the tables they change, the ones changed in a transaction once
+// more when it commits, and the records of a query are not cached if one
+// of its tables is invalidated while it runs. Changes made bypassing the
+// ORM are not seen by the cache. The records are deep copied in and out of
+// it.
+func WithQueryCache(c QueryCache) Option {
+	return func(s *Session) {
+		s.queryCache = c
+	}
+}
+
+// LRUQueryCache is the in-memory QueryCache, it holds up to a number of
+// queries and drops the least recently used ones.
+type LRUQueryCache struct {
+	mu     sync.Mutex
+	size   int
+	ll     *list.List // of *cachedQuery, most recently used first
+	items  map[string]*list.Element
+	hits   uint64
+	misses uint64
+}
+
+type cachedQuery struct {
+	key    string
+	value  interface{}
+	tables []string
+}
+
+var _ QueryCache = (*LRUQueryCache)(nil)
+
+// NewLRUQueryCache returns a cache holding up to size queries, 256 if size
+// is not positive.
+func NewLRUQueryCache(size int) *LRUQueryCache {
+	if size <= 0 {
+		size = 256
+	}
+	return &LRUQueryCache{size: size, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// Get implements QueryCache.
+func (c *LRUQueryCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.ll.MoveToFront(e)
+	return e.Value.(*cachedQuery).value, true
+}
+
+// Set implements QueryCache.
+func (c *LRUQueryCache) Set(key string, value interface{}, tables []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.ll.Remove(e)
+	}
+	c.items[key] = c.ll.PushFront(&cachedQuery{key: key, value: value, tables: tables})
+	for c.ll.Len() > c.size {
+		c.remove(c.ll.Back())
+	}
+}
+
+// Invalidate implements QueryCache.
+func (c *LRUQueryCache) Invalidate(tables ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for e := c.ll.Front(); e != nil; {
+		next := e.Next()
+		if intersects(e.Value.(*cachedQuery).tables, tables) {
+			c.remove(e)
+		}
+		e = next
+	}
+}
+
+// Stats returns the number of hits and misses, and of cached queries.
+func (c *LRUQueryCache) Stats() (hits, misses uint64, n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses, c.ll.Len()
+}
+
+func (c *LRUQueryCache) remove(e *list.Element) {
+	c.ll.Remove(e)
+	delete(c.items, e.Value.(*cachedQuery).key)
+}
+
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if strings.EqualFold(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cacheKey returns the key of a query, the types of vars are part of it as
+// 1 and "1" may not match the same rows.
+func cacheKey(sql string, vars []interface{}) string {
+	var key strings.Builder
+	key.WriteString(sql)
+	for _, v := range vars {
+		fmt.Fprintf(&key, "\x00%T:%v", v, v)
+	}
+	return key.String()
+}
+
+var tableRegexp = regexp.MustCompile("(?i)\\b(?:INTO|UPDATE|FROM|TABLE)\\s+(?:IF\\s+(?:NOT\\s+)?EXISTS\\s+)?[`\"]?(\\w+)")
+
+// changedTables returns the tables a statement may change, the one of its
+// model and the ones named by raw SQL.
+func changedTables(stmt *Statement) []string {
+	var tables []string
+	if stmt.Schema != nil {
+		tables = append(tables, stmt.Schema.Name)
+	}
+	if stmt.Op == OpRaw {
+		for _, m := range tableRegexp.FindAllStringSubmatch(stmt.SQL, -1) {
+			tables = append(tables, m[1])
+		}
+	}
+	return tables
+}
+
+// tableGenerations counts the invalidations of every table, so that the
+// records of a query are not cached if one of its tables was invalidated
+// while it ran: they may be the ones from before the change.
+var tableGenerations = &generations{n: make(map[string]uint64)}
+
+type generations struct {
+	mu sync.Mutex
+	n  map[string]uint64 // by lower case table name
+}
+
+// get returns the generation of tables, it grows with any of them.
+func (g *generations) get(tables []string) uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.sum(tables)
+}
+
+func (g *generations) sum(tables []string) uint64 {
+	var n uint64
+	for _, table := range tables {
+		n += g.n[strings.ToLower(table)]
+	}
+	return n
+}
+
+// invalidate moves tables to the next generation and invalidates them in c.
+func (g *generations) invalidate(c QueryCache, tables []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, table := range tables {
+		g.n[strings.ToLower(table)]++
+	}
+	c.Invalidate(tables...)
+}
+
+// set caches value in c unless tables moved past generation since it was
+// read before the query.
+func (g *generations) set(c QueryCache, key string, value interface{}, tables []string, generation uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.sum(tables) == generation {
+		c.Set(key, value, tables)
+	}
+}
+
+// txChanges collects the tables changed in a transaction, which are
+// invalidated again when it commits as the cache may have been filled
+// with the old records meanwhile.
+type txChanges struct {
+	mu     sync.Mutex
+	tables []string
+}
+
+func (t *txChanges) add(tables []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tables = append(t.tables, tables...)
+}
+
+// flush invalidates the tables changed once the transaction commits.
+func (t *txChanges) flush(c QueryCache) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.tables) > 0 {
+		tableGenerations.invalidate(c, t.tables)
+	}
+	t.tables = nil
+}
+
+// isRead reports whether query only reads, writes run as queries, like
+// INSERT ... RETURNING through QueryRows, invalidate the cache.
+func isRead(query string) bool {
+	verb := strings.ToUpper(strings.TrimSpace(query))
+	return strings.HasPrefix(verb, "SELECT") || strings.HasPrefix(verb, "EXPLAIN")
+}
+
+// deepCopy returns a copy of v sharing no pointer, slice or map with it, so
+// that cached records are not changed through the ones returned. Unexported
+// fields are copied as they are.
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return c
+	}
+	return v
+}
+
+// invalidate removes the entries of the tables changed by stmt.
+func (s *Session) invalidate(stmt *Statement) {
+	tables := changedTables(stmt)
+	if len(tables) == 0 {
+		return
+	}
+	tableGenerations.invalidate(s.queryCache, tables)
+	if s.tx != nil && s.txChanges != nil {
+		s.txChanges.add(tables)
+	}
+}
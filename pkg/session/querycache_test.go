@@ -0,0 +1,123 @@
+package session
+
+import "testing"
+
+func TestQueryCache(t *testing.T) {
+	cache := NewLRUQueryCache(0)
+	s := New(TestDB, TestDial, WithQueryCache(cache)).Model(&User{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(user1, user2)
+
+	find := func(q *Session) []User {
+		t.Helper()
+		var users []User
+		if err := q.Find(&users); err != nil {
+			t.Fatal(err)
+		}
+		return users
+	}
+	find(s)
+	users := find(s)
+	users[0].Age = 99
+	if hits, _, _ := cache.Stats(); hits != 1 || find(s)[0].Age != 18 {
+		t.Fatal("failed to find cached records")
+	}
+	if len(find(s.Where("Age > ?", 20))) != 1 || len(find(s.Where("Age > ?", 10))) != 2 {
+		t.Fatal("expect vars to be part of the key")
+	}
+
+	_, _ = s.Insert(user3)
+	if len(find(s)) != 3 {
+		t.Fatal("expect Insert to invalidate the table")
+	}
+	_, _ = s.Where("Name = ?", "Tom").Update("Age", 30)
+	if find(s.Where("Name = ?", "Tom"))[0].Age != 30 {
+		t.Fatal("expect Update to invalidate the table")
+	}
+	_, _ = s.Raw("DELETE FROM User WHERE Name = ?", "Jack").Exec()
+	if len(find(s)) != 2 {
+		t.Fatal("expect Exec to invalidate the table")
+	}
+	// like INSERT ... RETURNING, a write run as a query
+	rows, err := s.Raw("UPDATE User SET Age = ? WHERE Name = ?", 40, "Tom").QueryRows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	_ = rows.Close()
+	if find(s.Where("Name = ?", "Tom"))[0].Age != 40 {
+		t.Fatal("expect QueryRows of a write to invalidate the table")
+	}
+
+	tx := s.clone()
+	_ = tx.Begin()
+	_, _ = tx.Delete()
+	if len(find(tx)) != 0 {
+		t.Fatal("expect the cache to be skipped in transaction")
+	}
+	if len(find(s)) != 2 {
+		t.Fatal("expect uncommitted changes not to be seen")
+	}
+	_ = tx.Commit()
+	if len(find(s)) != 0 {
+		t.Fatal("expect Commit to invalidate the changed tables")
+	}
+}
+
+func TestQueryCache_InvalidatedWhileQuerying(t *testing.T) {
+	cache := NewLRUQueryCache(0)
+	callbacks := NewCallbacks()
+	// stands for a write by another session once the rows are queried,
+	// before they are read and cached
+	_ = callbacks.Query().After("orm:query").Register("write", func(stmt *Statement) {
+		tableGenerations.invalidate(stmt.Session.queryCache, []string{"User"})
+	})
+	s := New(TestDB, TestDial, WithQueryCache(cache)).Model(&User{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(user1, user2)
+
+	var users []User
+	if err := s.With(WithCallbacks(callbacks)).Find(&users); err != nil || len(users) != 2 {
+		t.Fatal("failed to find records, got", users, err)
+	}
+	if _, _, n := cache.Stats(); n != 0 {
+		t.Fatal("expect records read before an invalidation not to be cached, got", n)
+	}
+	users = nil
+	if err := s.Find(&users); err != nil || len(users) != 2 {
+		t.Fatal("failed to find records, got", users, err)
+	}
+	if _, _, n := cache.Stats(); n != 1 {
+		t.Fatal("expect records to be cached, got", n)
+	}
+}
+
+type Note struct {
+	ID   int `orm:"PRIMARY KEY"`
+	Body []byte
+}
+
+func TestQueryCache_Copy(t *testing.T) {
+	s := New(TestDB, TestDial, WithQueryCache(NewLRUQueryCache(0))).Model(&Note{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(&Note{1, []byte("abc")})
+
+	var notes []Note
+	_ = s.Find(&notes)
+	notes[0].Body[0] = 'x'
+	notes = nil
+	_ = s.Find(&notes)
+	if string(notes[0].Body) != "abc" {
+		t.Fatal("expect the scanned records not to share their slices with the cache, got", string(notes[0].Body))
+	}
+	notes[0].Body[0] = 'y'
+	notes = nil
+	_ = s.Find(&notes)
+	if string(notes[0].Body) != "abc" {
+		t.Fatal("expect the cached records not to share their slices, got", string(notes[0].Body))
+	}
+}
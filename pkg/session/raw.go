@@ -1,39 +1,152 @@
 package session
 
 import (
+	"context"
 	"database/sql"
-	"strings"
+	"time"
 
 	"github.com/fusidic/orm/pkg/clause"
 	"github.com/fusidic/orm/pkg/dialect"
 	"github.com/fusidic/orm/pkg/log"
 	"github.com/fusidic/orm/pkg/schema"
+	"github.com/fusidic/orm/pkg/trace"
 )
 
-// Session is the structure to operate database.
+// Session is the structure to operate database. Chain methods such as
+// Model, Where, Limit, OrderBy and Raw return a derived session and leave
+// the receiver unchanged, so that a configured session can be reused as a
+// base query, also from multiple goroutines. Derived sessions only share
+// the *sql.DB and the transaction begun before they were derived.
 type Session struct {
-	db       *sql.DB
-	dialect  dialect.Dialect
-	tx       *sql.Tx
-	refTable *schema.Schema
-	clause   clause.Clause
-	sql      strings.Builder
-	sqlVars  []interface{}
+	db        *sql.DB
+	dialect   dialect.Dialect
+	tx        *sql.Tx
+	refTable  *schema.Schema
+	clause    clause.Clause
+	orderBy   string
+	unscoped  bool
+	returning *returning
+	sql       string
+	sqlVars   []interface{}
+	err       error
+
+	cursorKey []byte
+	callbacks *Callbacks
+	stmtCache *StmtCache
+	// queryCache 缓存 Find 的结果，txChanges 记录事务中修改过的表
+	queryCache QueryCache
+	txChanges  *txChanges
+	dryRun     *dryRun
+	scanGuard  ScanGuard
+	// slowThreshold 以上的语句记入慢查询日志
+	slowThreshold time.Duration
+	ctx           context.Context
+	tracer        trace.Tracer
+	txSpan        trace.Span
+	txParent      context.Context // context before the transaction began
+	metrics       *Metrics
+	logger        log.Logger
+	interpolate   bool // 日志中的 SQL 是否代入参数
+	audit         bool // 记录增删改的审计日志
+	// supportReturning 由 engine 检查一次，nil 时每次询问 dialect
+	supportReturning *bool
 }
 
+// Option configures a Session, the options passed to orm.NewEngine are
+// applied to every session of the engine.
+type Option func(*Session)
+
 // New returns a session.
-func New(db *sql.DB, dialect dialect.Dialect) *Session {
-	return &Session{
-		db:      db,
-		dialect: dialect,
+func New(db *sql.DB, dialect dialect.Dialect, opts ...Option) *Session {
+	s := &Session{
+		db:        db,
+		dialect:   dialect,
+		cursorKey: defaultCursorKey,
+		callbacks: defaultCallbacks,
+		tracer:    trace.Noop,
+		logger:    log.Default(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// With returns a session with opts applied, e.g. to override an option of
+// the engine for one session: s.With(session.WithLogger(log.Discard)).
+func (s *Session) With(opts ...Option) *Session {
+	s = s.clone()
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WithLogger sets the logger of the session, log.Default() by default.
+func WithLogger(l log.Logger) Option {
+	return func(s *Session) {
+		s.logger = l
 	}
 }
 
-// Clear reset sql Vars
+// LoggerOf returns the logger set by WithLogger among opts, log.Default()
+// if none, e.g. for the engine to log before it has a database.
+func LoggerOf(opts ...Option) log.Logger {
+	probe := Session{logger: log.Default()}
+	for _, opt := range opts {
+		opt(&probe)
+	}
+	return probe.logger
+}
+
+// Logger returns the logger of the session.
+func (s *Session) Logger() log.Logger {
+	return s.logger
+}
+
+// clone returns a copy of the session which can be changed without
+// affecting s.
+func (s *Session) clone() *Session {
+	c := *s
+	c.clause = s.clause.Clone()
+	// force append to copy instead of writing to the shared array
+	c.sqlVars = s.sqlVars[:len(s.sqlVars):len(s.sqlVars)]
+	return &c
+}
+
+// Clear reset sql Vars, it changes s in place and should only be used on a
+// session that is not shared.
 func (s *Session) Clear() {
-	s.sql.Reset()
+	s.sql = ""
 	s.sqlVars = nil
 	s.clause = clause.Clause{}
+	s.orderBy = ""
+	s.unscoped = false
+	s.returning = nil
+	s.err = nil
+}
+
+// Err returns the first error met while building the statement, e.g. a
+// missing named parameter. Exec and QueryRows return it instead of running
+// the statement.
+func (s *Session) Err() error {
+	return s.err
+}
+
+// bindNamed rewrites the named parameters of query if its only arg is a map
+// or a struct, otherwise it returns query and args as they are.
+func (s *Session) bindNamed(query string, args []interface{}) (string, []interface{}, bool) {
+	if len(args) != 1 || !clause.IsNamedArg(args[0]) || !clause.HasNamed(query) {
+		return query, args, false
+	}
+	sql, vars, err := clause.Named(query, args[0])
+	if err != nil {
+		if s.err == nil {
+			s.err = err
+		}
+		return query, nil, true
+	}
+	return sql, vars, true
 }
 
 // CommonDB is a minimal function set of db
@@ -54,38 +167,103 @@ func (s *Session) DB() CommonDB {
 	return s.db
 }
 
-// Raw convert string to SQL
+// contextDB is the function set of db taking a context
+type contextDB interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// conn is DB with the functions taking a context.
+func (s *Session) conn() contextDB {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+// Raw convert string to SQL, named parameters like @name or :name are bound
+// from a single map[string]interface{} or struct value.
 func (s *Session) Raw(sql string, values ...interface{}) *Session {
-	s.sql.WriteString(sql)
-	s.sql.WriteString(" ")
+	s = s.clone()
+	sql, values, _ = s.bindNamed(sql, values)
+	s.sql += sql + " "
 	s.sqlVars = append(s.sqlVars, values...)
 	return s
 }
 
 // Exec raw sql with sqlVars
-func (s *Session) Exec() (result sql.Result, err error) {
-	defer s.Clear()
-	log.Info(s.sql.String(), s.sqlVars)
-	// Watch out it call DB() here.
-	if result, err = s.DB().Exec(s.sql.String(), s.sqlVars...); err != nil {
-		log.Error(err)
+func (s *Session) Exec() (sql.Result, error) {
+	stmt := s.run(OpRaw, modeExec)
+	return stmt.Result, stmt.Err
+}
+
+// Row is the result of QueryRow, its Scan returns the error of a statement
+// which has not been run, e.g. a missing named parameter or ErrDryRun.
+type Row struct {
+	row *sql.Row
+	err error
+}
+
+// Scan copies the columns of the row into dest like sql.Row.Scan.
+func (r *Row) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.row.Scan(dest...)
+}
+
+// Err returns the error of the statement, as Scan would.
+func (r *Row) Err() error {
+	if r.err != nil {
+		return r.err
 	}
-	return
+	return r.row.Err()
 }
 
-// QueryRow gets a record from db
-func (s *Session) QueryRow() *sql.Row {
-	defer s.Clear()
-	log.Info(s.sql.String(), s.sqlVars)
-	return s.DB().QueryRow(s.sql.String(), s.sqlVars...)
+// QueryRow gets a record from db.
+func (s *Session) QueryRow() *Row {
+	stmt := s.run(OpRaw, modeQueryRow)
+	if stmt.Err == nil && s.dryRun != nil {
+		return &Row{err: ErrDryRun}
+	}
+	return &Row{row: stmt.Row, err: stmt.Err}
 }
 
 // QueryRows gets a list of records from db.
-func (s *Session) QueryRows() (rows *sql.Rows, err error) {
-	defer s.Clear()
-	log.Info(s.sql.String(), s.sqlVars)
-	if rows, err = s.DB().Query(s.sql.String(), s.sqlVars...); err != nil {
-		log.Error(err)
+func (s *Session) QueryRows() (*sql.Rows, error) {
+	stmt := s.run(OpRaw, modeQuery)
+	if stmt.Err == nil && s.dryRun != nil {
+		return nil, ErrDryRun
+	}
+	return stmt.Rows, stmt.Err
+}
+
+// run passes the statement of the session through the callbacks of op. The
+// SQL is the one of Raw if any, otherwise the callbacks build it from the
+// clause in orders.
+func (s *Session) run(op Operation, m mode, orders ...clause.Type) *Statement {
+	stmt := s.statement(op, m, orders...)
+	s.runStatement(stmt, false)
+	return stmt
+}
+
+func (s *Session) statement(op Operation, m mode, orders ...clause.Type) *Statement {
+	// callbacks may change the clause, which is shared with derived sessions
+	c := s.clause.Clone()
+	stmt := &Statement{
+		Session: s,
+		Op:      op,
+		Schema:  s.refTable,
+		Clause:  &c,
+		Orders:  orders,
+		SQL:     s.sql,
+		Vars:    s.sqlVars,
+		Err:     s.err,
+		mode:    m,
+	}
+	if stmt.Err == nil && s.refTable != nil {
+		stmt.Err = s.refTable.Err
 	}
-	return
+	return stmt
 }
@@ -1,11 +1,15 @@
 package session
 
 import (
+	"bytes"
 	"database/sql"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/fusidic/orm/pkg/dialect"
+	"github.com/fusidic/orm/pkg/log"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -45,3 +49,64 @@ func TestSession_QueryRows(t *testing.T) {
 		t.Fatal("failed to query db", err)
 	}
 }
+
+func TestSession_RawNamed(t *testing.T) {
+	s := NewSession()
+	_, _ = s.Raw("DROP TABLE IF EXISTS User;").Exec()
+	_, _ = s.Raw("CREATE TABLE User(Name text, Age integer);").Exec()
+	_, err := s.Raw("INSERT INTO User(`Name`, `Age`) values (@Name, @Age)", &User{"Tom", 18}).Exec()
+	if err != nil {
+		t.Fatal("failed to bind struct fields", err)
+	}
+	var count int
+	args := map[string]interface{}{"names": []string{"Tom", "Sam"}, "age": 18}
+	if err := s.Raw("SELECT count(*) FROM User WHERE Name IN (:names) AND Age = :age", args).QueryRow().Scan(&count); err != nil || count != 1 {
+		t.Fatal("failed to bind named parameters", err)
+	}
+	if _, err := s.Raw("SELECT * FROM User WHERE Name = @name", map[string]interface{}{}).QueryRows(); err == nil {
+		t.Fatal("expect error of missing parameter")
+	}
+	if err := s.Raw("SELECT count(*) FROM User WHERE Name = @name", map[string]interface{}{}).QueryRow().Scan(&count); err == nil ||
+		!strings.Contains(err.Error(), "missing value of named parameter") {
+		t.Fatal("expect QueryRow to report the missing parameter, got", err)
+	}
+	if _, err := s.Model(&User{}).Where("Name = @name", map[string]interface{}{"name": "Tom", "x": 1}).Count(); err == nil {
+		t.Fatal("expect error of unused parameter")
+	}
+}
+
+func TestSession_WithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	s := New(TestDB, TestDial, WithLogger(log.NewJSON(&buf, log.InfoLevel)))
+	_, _ = s.Raw("SELECT ?", 1).Exec()
+	if out := buf.String(); !strings.Contains(out, `"msg":"statement","sql":"SELECT ?","vars":[1]`) {
+		t.Fatal("failed to log with the logger of the session, got", out)
+	}
+	buf.Reset()
+	_, _ = s.With(WithLogger(log.Discard)).Raw("SELECT 1").Exec()
+	if buf.Len() != 0 || s.Logger() == log.Discard {
+		t.Fatal("failed to override the logger of one session")
+	}
+	if LoggerOf(WithAudit(), WithLogger(log.Discard)) != log.Discard || LoggerOf(WithAudit()) != log.Default() {
+		t.Fatal("failed to collect the logger of the options")
+	}
+}
+
+// numbered is SQLite with numbered placeholders ?1, ?2 like $1, $2 of postgres
+type numbered struct{ dialect.Dialect }
+
+func (numbered) BindVar(i int) string { return fmt.Sprintf("?%d", i) }
+
+func TestSession_NumberedBindVars(t *testing.T) {
+	s := New(TestDB, numbered{TestDial}).Model(&User{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(&User{"Tom", 18}, &User{"Sam", 25})
+	if _, err := s.Where("Name = :name", map[string]interface{}{"name": "Tom"}).Update("Age", 30); err != nil {
+		t.Fatal(err)
+	}
+	u := &User{}
+	if err := s.Where("Age = ?", 30).First(u); err != nil || u.Name != "Tom" {
+		t.Fatal("failed to bind the vars in order, got", u, err)
+	}
+}
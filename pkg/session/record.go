@@ -1,71 +1,284 @@
 package session
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/fusidic/orm/pkg/clause"
+	"github.com/fusidic/orm/pkg/schema"
 )
 
-// Insert one or more records in database.
+// ErrRecordNotFound is returned by First if no record matches.
+var ErrRecordNotFound = errors.New("record not found")
+
+// Insert one or more records in database. A BeforeInsert hook that fails
+// stops the insert. The records are validated once the hooks have set
+// them, the error of the first invalid one is a *schema.ValidationError
+// listing every failing field. The keys generated for zero auto increment
+// fields are set back on the records before the AfterInsert hooks.
 func (s *Session) Insert(values ...interface{}) (int64, error) {
-	recordValues := make([]interface{}, 0)
+	if s.audit && len(values) > 0 && s.Model(values[0]).audits() {
+		return s.audited(OpCreate, values, nil, func(tx *Session) (int64, error) {
+			return tx.Insert(values...)
+		})
+	}
+	rows := make([][]interface{}, 0, len(values))
+	s = s.clone()
 	for _, value := range values {
 		// hook
-		s.CallMethod(BeforeInsert, value)
-		table := s.Model(value).GetRefTable()
-		s.clause.Set(clause.INSERT, table.Name, table.FieldNames)
+		if err := s.Model(value).CallMethod(BeforeInsert, value); err != nil {
+			return 0, err
+		}
+	}
+	for _, value := range values {
+		s = s.Model(value)
+		if err := s.validate(value); err != nil {
+			return 0, err
+		}
 		// 将对象 value 转换，并添加到 VALUES 中
-		recordValues = append(recordValues, table.RecordValues(value))
+		rows = append(rows, s.GetRefTable().RecordValues(value))
+	}
+	table := s.GetRefTable()
+	columns, err := s.setReturning()
+	if err != nil {
+		return 0, err
+	}
+	var affected int64
+	if columns != nil {
+		names, recordValues := insertValues(table, rows)
+		s.clause.Set(clause.INSERT, table.Name, names)
+		s.clause.Set(clause.VALUES, recordValues...)
+		affected, err = s.execChange(OpCreate, columns, values, clause.INSERT, clause.VALUES, clause.RETURNING)
+	} else if len(rows) > 1 && s.tx == nil && s.dryRun == nil && len(generatedRows(table, rows)) > 0 {
+		return s.insertInTx(table, values, rows)
+	} else {
+		affected, err = s.insertRows(table, values, rows)
+	}
+	if err != nil {
+		return affected, err
 	}
+	return affected, s.callAfter(AfterInsert, values)
+}
 
+// insertInTx inserts rows which are inserted one by one, see insertRows,
+// and calls the AfterInsert hooks of records in a transaction of a copy of
+// s, committed once the hooks succeed.
+func (s *Session) insertInTx(table *schema.Schema, records []interface{}, rows [][]interface{}) (affected int64, err error) {
+	tx := s.clone()
+	if err = tx.Begin(); err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+	if affected, err = tx.insertRows(table, records, rows); err != nil {
+		return affected, err
+	}
+	return affected, tx.callAfter(AfterInsert, records)
+}
+
+// insertRows inserts rows, the values of records, and sets the keys the
+// database generates for their zero auto increment field back on them. As
+// only the key of the last row inserted is reported, rows are inserted one
+// by one if more than one needs a key, which Insert does in a transaction
+// unless s is in one.
+func (s *Session) insertRows(table *schema.Schema, records []interface{}, rows [][]interface{}) (affected int64, err error) {
+	i := autoIncrementIndex(table)
+	generated := generatedRows(table, rows)
+	if len(rows) > 1 && len(generated) > 0 {
+		for j := range rows {
+			n, err := s.insertRows(table, records[j:j+1], rows[j:j+1])
+			affected += n
+			if err != nil {
+				return affected, err
+			}
+		}
+		return affected, nil
+	}
+	names, recordValues := insertValues(table, rows)
+	s.clause.Set(clause.INSERT, table.Name, names)
 	s.clause.Set(clause.VALUES, recordValues...)
-	sql, vars := s.clause.Build(clause.INSERT, clause.VALUES)
-	result, err := s.Raw(sql, vars...).Exec()
+	stmt := s.run(OpCreate, modeExec, clause.INSERT, clause.VALUES)
+	if stmt.Err != nil {
+		return 0, stmt.Err
+	}
+	if affected, err = stmt.Result.RowsAffected(); err != nil {
+		return affected, err
+	}
+	if len(generated) == 0 || s.dryRun != nil {
+		return affected, nil
+	}
+	id, err := stmt.Result.LastInsertId()
 	if err != nil {
-		return 0, err
+		return affected, err
+	}
+	field := reflect.Indirect(reflect.ValueOf(records[0])).FieldByName(table.Fields[i].Name)
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		field.SetInt(id)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.SetUint(uint64(id))
+	}
+	return affected, nil
+}
+
+// generatedRows returns the indexes of the rows whose auto increment field
+// is zero, the database generates their key.
+func generatedRows(table *schema.Schema, rows [][]interface{}) []int {
+	i := autoIncrementIndex(table)
+	if i < 0 {
+		return nil
+	}
+	var generated []int
+	for j, row := range rows {
+		if isZero(row[i]) {
+			generated = append(generated, j)
+		}
 	}
-	s.CallMethod(AfterInsert, nil)
-	return result.RowsAffected()
+	return generated
+}
+
+// autoIncrementIndex returns the index of the auto increment field of
+// table, -1 if there is none.
+func autoIncrementIndex(table *schema.Schema) int {
+	for i, field := range table.Fields {
+		if field.AutoIncrement {
+			return i
+		}
+	}
+	return -1
+}
+
+func isZero(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	return !v.IsValid() || v.IsZero()
+}
+
+// insertValues returns the columns and values of the rows to insert, the
+// auto increment columns are left to the database if they are zero in
+// every row, and bound to NULL in the rows where they are zero otherwise.
+func insertValues(table *schema.Schema, rows [][]interface{}) ([]string, []interface{}) {
+	var skip []bool
+	for i, field := range table.Fields {
+		zero := field.AutoIncrement
+		for _, row := range rows {
+			zero = zero && isZero(row[i])
+		}
+		skip = append(skip, zero)
+	}
+	var names []string
+	for i, name := range table.FieldNames {
+		if !skip[i] {
+			names = append(names, name)
+		}
+	}
+	values := make([]interface{}, len(rows))
+	for j, row := range rows {
+		var kept []interface{}
+		for i, v := range row {
+			switch {
+			case skip[i]:
+				continue
+			case table.Fields[i].AutoIncrement && isZero(v):
+				v = nil
+			}
+			kept = append(kept, v)
+		}
+		values[j] = kept
+	}
+	return names, values
 }
 
 // Find gets all eligible records and put them into objects.
-func (s *Session) Find(values interface{}) error {
-	// hook
-	s.CallMethod(BeforeQuery, nil)
+func (s *Session) Find(values interface{}) (err error) {
 	// destSlice.Type().Elem() 获取切片的单个元素的类型 destType，
 	// 使用 reflect.New() 方法创建一个 destType 的实例，作为 Model() 的入参，
 	// 映射出表结构 RefTable()
 	destSlice := reflect.Indirect((reflect.ValueOf(values))) // []User{}
 	destType := destSlice.Type().Elem()                      // User{}
-	table := s.Model(reflect.New(destType).Elem().Interface()).GetRefTable()
+	s = s.Model(reflect.New(destType).Elem().Interface()).applyDefaultScope()
+	table := s.GetRefTable()
+	// hook
+	if err := s.CallMethod(BeforeQuery, reflect.New(destType).Interface()); err != nil {
+		return err
+	}
 
 	// 根据表结构，使用 clause 构造出 SELECT 语句，查询到所有符合条件的记录 rows
 	s.clause.Set(clause.SELECT, table.Name, table.FieldNames)
-	sql, vars := s.clause.Build(clause.SELECT, clause.WHERE, clause.ORDERBY, clause.LIMIT)
-	rows, err := s.Raw(sql, vars...).QueryRows()
-	if err != nil {
-		return err
+	stmt := s.statement(OpQuery, modeQuery, clause.SELECT, clause.WHERE, clause.ORDERBY, clause.LIMIT, clause.OFFSET, clause.LOCKING)
+	stmt.find = true
+	if s.queryCache != nil && s.tx == nil {
+		stmt.cacheTables = []string{table.Name}
+		stmt.cacheKey = destType.String() + "\x00"
+	}
+	s.runStatement(stmt, true)
+	if stmt.Err != nil {
+		return stmt.Err
 	}
+	before := destSlice.Len()
+	defer func() { stmt.endSpan(int64(destSlice.Len()-before), err) }()
+	if s.dryRun != nil {
+		return nil
+	}
+	if stmt.cached != nil {
+		cached := reflect.ValueOf(stmt.cached)
+		for i := 0; i < cached.Len(); i++ {
+			dest := reflect.New(destType).Elem()
+			dest.Set(deepCopy(cached.Index(i)))
+			if err := s.callAfter(AfterQuery, []interface{}{dest.Addr().Interface()}); err != nil {
+				return err
+			}
+			destSlice.Set(reflect.Append(destSlice, dest))
+		}
+		return nil
+	}
+	rows := stmt.Rows
+	// the records are cached as they are scanned, before the hooks change
+	// them, copies sharing no slice or map with the ones returned
+	found := reflect.MakeSlice(destSlice.Type(), 0, 0)
 
 	// 遍历每一行记录，利用反射创建 destType 的实例 dest，将 dest 的所有字段平铺开，构造切片 value
 	for rows.Next() {
 		dest := reflect.New(destType).Elem()
 		var value []interface{}
-		for _, name := range table.FieldNames {
-			value = append(value, dest.FieldByName(name).Addr().Interface())
+		for _, field := range table.Fields {
+			value = append(value, dest.FieldByName(field.Name).Addr().Interface())
 		}
 		// 调用 rows.Scan() 将该行记录每一列的值依次赋值给 value 中的每一个字段
 		if err := rows.Scan(value...); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if stmt.cacheTables != nil {
+			found = reflect.Append(found, deepCopy(dest))
+		}
+		if err := s.callAfter(AfterQuery, []interface{}{dest.Addr().Interface()}); err != nil {
+			_ = rows.Close()
 			return err
 		}
-		s.CallMethod(AfterQuery, dest.Addr().Interface())
 		destSlice.Set(reflect.Append(destSlice, dest))
 	}
-	return rows.Close()
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if stmt.cacheTables != nil && rows.Err() == nil {
+		tableGenerations.set(s.queryCache, stmt.cacheKey, found.Interface(), stmt.cacheTables, stmt.generation)
+	}
+	return nil
 }
 
-// Update requires kv map or kv list.
+// Update requires kv map or kv list. The values are validated by the rules
+// of their fields first, and the matched records with the values set by
+// their Validate, which fails with a *schema.ValidationError. The
+// Update hooks get the matched records, selected before the update and
+// again after it, or the updated records if they are scanned by Returning.
 func (s *Session) Update(kv ...interface{}) (int64, error) {
 	// 判定入参为 map
 	m, ok := kv[0].(map[string]interface{})
@@ -76,56 +289,257 @@ func (s *Session) Update(kv ...interface{}) (int64, error) {
 			m[kv[i].(string)] = kv[i+1]
 		}
 	}
+	if s.audits() {
+		return s.audited(OpUpdate, nil, m, func(tx *Session) (int64, error) {
+			return tx.Update(m)
+		})
+	}
+	s = s.applyDefaultScope()
+	hooks := []Hook{BeforeUpdate}
+	if !s.returnsRecords() {
+		hooks = append(hooks, AfterUpdate)
+	}
+	var records []interface{}
+	if s.implements(hooks...) || isValidator(s.GetRefTable().Model) {
+		var err error
+		if records, err = s.matchedRecords(); err != nil {
+			return 0, err
+		}
+	}
+	if err := s.validateValues(m, records); err != nil {
+		return 0, err
+	}
+	for _, record := range records {
+		if err := s.CallMethod(BeforeUpdate, record); err != nil {
+			return 0, err
+		}
+	}
 	s.clause.Set(clause.UPDATE, s.GetRefTable().Name, m)
-	sql, vars := s.clause.Build(clause.UPDATE, clause.WHERE)
-	result, err := s.Raw(sql, vars...).Exec()
+	columns, err := s.setReturning()
 	if err != nil {
 		return 0, err
 	}
-	return result.RowsAffected()
+	affected, err := s.execChange(OpUpdate, columns, nil, clause.UPDATE, clause.WHERE, clause.RETURNING)
+	if err != nil {
+		return affected, err
+	}
+	if s.returnsRecords() {
+		return affected, s.callAfter(AfterUpdate, s.changedRecords(affected))
+	}
+	if records, err = s.reload(records); err != nil {
+		return affected, err
+	}
+	return affected, s.callAfter(AfterUpdate, records)
+}
+
+// Expr returns a SQL expression which is rendered inline by Update and
+// Insert instead of being bound as a value, e.g. Update("Stock", Expr("Stock - ?", 1)).
+func Expr(sql string, args ...interface{}) clause.Expr {
+	return clause.Expr{SQL: sql, Vars: args}
 }
 
-// Delete records with where clause
+// Increment atomically adds n to column of the records matched by the where
+// clause.
+func (s *Session) Increment(column string, n interface{}) (int64, error) {
+	return s.Update(column, Expr(column+" + ?", n))
+}
+
+// Decrement atomically subtracts n from column of the records matched by the
+// where clause.
+func (s *Session) Decrement(column string, n interface{}) (int64, error) {
+	return s.Update(column, Expr(column+" - ?", n))
+}
+
+// Delete records with where clause. The Delete hooks get the matched
+// records, selected before the delete, or the deleted records if they are
+// scanned by Returning.
 func (s *Session) Delete() (int64, error) {
-	s.CallMethod(BeforeDelete, nil)
+	if s.audits() {
+		return s.audited(OpDelete, nil, nil, func(tx *Session) (int64, error) {
+			return tx.Delete()
+		})
+	}
+	s = s.applyDefaultScope()
+	hooks := []Hook{BeforeDelete}
+	if !s.returnsRecords() {
+		hooks = append(hooks, AfterDelete)
+	}
+	var records []interface{}
+	if s.implements(hooks...) {
+		var err error
+		if records, err = s.matchedRecords(); err != nil {
+			return 0, err
+		}
+	}
+	for _, record := range records {
+		if err := s.CallMethod(BeforeDelete, record); err != nil {
+			return 0, err
+		}
+	}
 	s.clause.Set(clause.DELETE, s.GetRefTable().Name)
-	sql, vars := s.clause.Build(clause.DELETE, clause.WHERE)
-	result, err := s.Raw(sql, vars...).Exec()
+	columns, err := s.setReturning()
 	if err != nil {
 		return 0, err
 	}
-	s.CallMethod(AfterDelete, nil)
-	return result.RowsAffected()
+	affected, err := s.execChange(OpDelete, columns, nil, clause.DELETE, clause.WHERE, clause.RETURNING)
+	if err != nil {
+		return affected, err
+	}
+	if s.returnsRecords() {
+		return affected, s.callAfter(AfterDelete, s.changedRecords(affected))
+	}
+	return affected, s.callAfter(AfterDelete, records)
+}
+
+// execChange runs an INSERT, UPDATE or DELETE built from the clause in
+// orders, and returns the number of affected rows. If columns are returned,
+// they are scanned like Returning.
+func (s *Session) execChange(op Operation, columns []string, records []interface{}, orders ...clause.Type) (int64, error) {
+	if columns != nil {
+		if err := s.checkReturning(records); err != nil {
+			return 0, err
+		}
+		stmt := s.statement(op, modeQuery, orders...)
+		s.runStatement(stmt, true)
+		if stmt.Err != nil {
+			return 0, stmt.Err
+		}
+		if s.dryRun != nil {
+			stmt.endSpan(0, nil)
+			return 0, nil
+		}
+		affected, err := s.scanReturning(stmt.Rows, columns, records)
+		stmt.endSpan(affected, err)
+		return affected, err
+	}
+	stmt := s.run(op, modeExec, orders...)
+	if stmt.Err != nil {
+		return 0, stmt.Err
+	}
+	return stmt.Result.RowsAffected()
+}
+
+// queryRow runs a query built from the clause in orders, and scans its
+// first row into dest. It returns sql.ErrNoRows if there is none, as in dry
+// run mode.
+func (s *Session) queryRow(orders []clause.Type, dest ...interface{}) (err error) {
+	stmt := s.statement(OpQuery, modeQuery, orders...)
+	s.runStatement(stmt, true)
+	if stmt.Err != nil {
+		return stmt.Err
+	}
+	found := int64(0)
+	defer func() { stmt.endSpan(found, err) }()
+	if s.dryRun != nil {
+		return sql.ErrNoRows
+	}
+	rows := stmt.Rows
+	defer func() { _ = rows.Close() }()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	found = 1
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+	return rows.Close()
 }
 
 // Count records with where clause
 func (s *Session) Count() (int64, error) {
+	s = s.applyDefaultScope()
 	s.clause.Set(clause.COUNT, s.GetRefTable().Name)
-	sql, vars := s.clause.Build(clause.COUNT, clause.WHERE)
-	row := s.Raw(sql, vars...).QueryRow()
 	var tmp int64
-	if err := row.Scan(&tmp); err != nil {
+	if err := s.queryRow([]clause.Type{clause.COUNT, clause.WHERE}, &tmp); err != nil && err != sql.ErrNoRows {
 		return 0, err
 	}
 	return tmp, nil
 }
 
+// Exists reports whether any record matches the where clause, it runs
+// SELECT 1 ... LIMIT 1 instead of counting all of them.
+func (s *Session) Exists() (bool, error) {
+	s = s.applyDefaultScope()
+	s.clause.Set(clause.SELECT, s.GetRefTable().Name, []string{"1"})
+	s.clause.Set(clause.LIMIT, 1)
+	var tmp int
+	if err := s.queryRow([]clause.Type{clause.SELECT, clause.WHERE, clause.LIMIT}, &tmp); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 // Limit adds limit condition to clause
 func (s *Session) Limit(num int) *Session {
+	s = s.clone()
 	s.clause.Set(clause.LIMIT, num)
 	return s
 }
 
-// Where adds limit condition to clause
-func (s *Session) Where(desc string, args ...interface{}) *Session {
+// Offset adds offset condition to clause, it should be used together
+// with Limit.
+func (s *Session) Offset(num int) *Session {
+	s = s.clone()
+	s.clause.Set(clause.OFFSET, num)
+	return s
+}
+
+// Locking adds a row locking clause like FOR UPDATE SKIP LOCKED to the
+// SELECT of Find and First, which should run inside a transaction. It is a
+// no-op if the dialect doesn't lock rows, like SQLite which locks the
+// whole database.
+func (s *Session) Locking(l clause.Locking) *Session {
+	s = s.clone()
+	if !s.dialect.SupportLocking() {
+		s.logger.Info(s.Context(), "row locking is ignored by the dialect", "strength", l.Strength)
+		return s
+	}
+	s.clause.Set(clause.LOCKING, l)
+	return s
+}
+
+// Where adds where condition to clause, query is either a SQL string with
+// args, in which a slice arg expands to one placeholder per element, or a
+// clause.Condition. Like Raw, the string may use named parameters.
+// Conditions of multiple Where calls are joined by AND, empty ones are
+// skipped. A query of another type makes the statement fail.
+func (s *Session) Where(query interface{}, args ...interface{}) *Session {
 	// input: (condition), (vars)
+	s = s.clone()
+	var desc string
 	var vars []interface{}
-	s.clause.Set(clause.WHERE, append(append(vars, desc), args...)...)
+	switch q := query.(type) {
+	case string:
+		var named bool
+		if desc, vars, named = s.bindNamed(q, args); !named {
+			desc, vars = clause.Expand(q, args)
+		}
+	case clause.Condition:
+		desc, vars = q.Build()
+	default:
+		// 不能丢掉条件，否则 Update 和 Delete 会作用于所有记录
+		if s.err == nil {
+			s.err = fmt.Errorf("unsupported where condition %T", query)
+		}
+		return s
+	}
+	if strings.TrimSpace(desc) == "" {
+		return s
+	}
+	s.clause.And(desc, vars...)
 	return s
 }
 
 // OrderBy adds order by condition to clause
 func (s *Session) OrderBy(desc string) *Session {
+	s = s.clone()
+	s.orderBy = desc
 	s.clause.Set(clause.ORDERBY, desc)
 	return s
 }
@@ -139,7 +553,7 @@ func (s *Session) First(value interface{}) error {
 	}
 
 	if destSlice.Len() == 0 {
-		return errors.New("NOT FOUND")
+		return ErrRecordNotFound
 	}
 	dest.Set(destSlice.Index(0))
 	return nil
@@ -1,6 +1,12 @@
 package session
 
-import "testing"
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/fusidic/orm/pkg/clause"
+)
 
 var (
 	user1 = &User{"Tom", 18}
@@ -28,6 +34,33 @@ func TestSession_Insert(t *testing.T) {
 	}
 }
 
+func TestSession_InsertAutoIncrement(t *testing.T) {
+	s := NewSession().Model(&Profile{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	tom := &Profile{Name: "Tom"}
+	if _, err := s.Insert(tom); err != nil || tom.ID != 1 {
+		t.Fatal("failed to set the generated ID back, got", tom.ID, err)
+	}
+	profiles := []*Profile{{Name: "Sam"}, {ID: 5, Name: "Jack"}, {Name: "Amy"}}
+	if affected, err := s.Insert(profiles[0], profiles[1], profiles[2]); err != nil || affected != 3 {
+		t.Fatal("failed to insert mixed rows", affected, err)
+	}
+	if profiles[0].ID != 2 || profiles[1].ID != 5 || profiles[2].ID != 6 {
+		t.Fatal("failed to set the generated IDs back, got", profiles[0].ID, profiles[1].ID, profiles[2].ID)
+	}
+	_, values := insertValues(s.GetRefTable(), [][]interface{}{{0, "Sam"}, {5, "Jack"}})
+	if !reflect.DeepEqual(values, []interface{}{[]interface{}{nil, "Sam"}, []interface{}{5, "Jack"}}) {
+		t.Fatal("expect zero IDs to be bound to NULL, got", values)
+	}
+	if _, err := s.Insert(&Profile{Name: "Bob"}, &Profile{Name: "Tom"}); err == nil {
+		t.Fatal("expect the unique constraint to fail")
+	}
+	if count, _ := s.Count(); count != 4 {
+		t.Fatal("expect the rows inserted one by one to be rolled back, got", count)
+	}
+}
+
 func TestSession_Find(t *testing.T) {
 	s := testRecordInit(t)
 	var users []User
@@ -62,3 +95,101 @@ func TestSession_DeleteAndCount(t *testing.T) {
 		t.Fatal("failed to count")
 	}
 }
+
+func TestSession_Where(t *testing.T) {
+	s := testRecordInit(t)
+	_, _ = s.Insert(user3)
+	var users []User
+	if err := s.Where("Name IN (?)", []string{"Tom", "Jack"}).Where("Age > ?", 20).Find(&users); err != nil ||
+		len(users) != 1 || users[0].Name != "Jack" {
+		t.Fatal("failed to query with IN list, got", users)
+	}
+	users = nil
+	if err := s.Where(clause.Or(clause.Eq("Name", "Tom"), clause.Gt("Age", 20))).
+		Where(clause.NotIn("Name", []string{"Sam"})).Find(&users); err != nil || len(users) != 2 {
+		t.Fatal("failed to query with conditions, got", users)
+	}
+
+	var count int64
+	users = nil
+	if err := s.Where("Name IN (?)", []string{}).Find(&users); err != nil || len(users) != 0 {
+		t.Fatal("expect IN an empty list to match nothing, got", users, err)
+	}
+	if count, _ = s.Where("Name NOT IN (?)", []string{}).Count(); count != 3 {
+		t.Fatal("expect NOT IN an empty list to match everything, got", count)
+	}
+	if count, _ = s.Where("Name NOT IN (@names)", map[string]interface{}{"names": []string{}}).Count(); count != 3 {
+		t.Fatal("expect NOT IN an empty named list to match everything, got", count)
+	}
+}
+
+func TestSession_WhereInvalid(t *testing.T) {
+	s := testRecordInit(t)
+	if _, err := s.Where(struct{ Name string }{"Tom"}).Delete(); err == nil {
+		t.Fatal("expect an unsupported condition to fail")
+	}
+	if _, err := s.Where(User{}).Update("Age", 30); err == nil {
+		t.Fatal("expect an unsupported condition to fail")
+	}
+	var users []User
+	if err := s.Where(clause.And()).Where(clause.Or(clause.And(), clause.Eq("Name", "Tom"))).Find(&users); err != nil ||
+		len(users) != 1 {
+		t.Fatal("expect empty conditions to be skipped, got", users, err)
+	}
+	if count, _ := s.Where("Age = ?", 30).Count(); count != 0 {
+		t.Fatal("expect no record to be updated")
+	}
+	if count, _ := s.Count(); count != 2 {
+		t.Fatal("expect no record to be deleted, got", count)
+	}
+}
+
+func TestSession_Chain(t *testing.T) {
+	s := testRecordInit(t)
+	_, _ = s.Insert(user3)
+	base := s.Where("Age = ?", 25)
+	if count, _ := base.Where("Name = ?", "Sam").Count(); count != 1 {
+		t.Fatal("failed to derive query, got", count)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if count, err := base.Count(); err != nil || count != 2 {
+				t.Error("base query is changed by derived ones, got", count, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSession_Increment(t *testing.T) {
+	s := testRecordInit(t)
+	if affected, err := s.Where("Name = ?", "Tom").Increment("Age", 2); err != nil || affected != 1 {
+		t.Fatal("failed to increment", err)
+	}
+	if _, err := s.Where("Name = ?", "Sam").Update(map[string]interface{}{"Age": Expr("Age * ?", 2)}); err != nil {
+		t.Fatal("failed to update with expression", err)
+	}
+	_, _ = s.Where("Name = ?", "Sam").Decrement("Age", 10)
+	var users []User
+	_ = s.OrderBy("Name ASC").Find(&users)
+	if len(users) != 2 || users[0].Age != 40 || users[1].Age != 20 {
+		t.Fatal("failed to update with expressions, got", users)
+	}
+}
+
+func TestSession_Locking(t *testing.T) {
+	s := testRecordInit(t)
+	if err := s.Begin(); err != nil {
+		t.Fatal("failed to begin", err)
+	}
+	defer func() { _ = s.Rollback() }()
+	u := &User{}
+	lock := clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked}
+	if err := s.Where("Name = ?", "Tom").Locking(lock).First(u); err != nil || u.Age != 18 {
+		t.Fatal("expect locking to be ignored by sqlite, got", u, err)
+	}
+}
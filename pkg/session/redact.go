@@ -0,0 +1,158 @@
+package session
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/fusidic/orm/pkg/clause"
+	"github.com/fusidic/orm/pkg/log"
+	"github.com/fusidic/orm/pkg/schema"
+)
+
+// Redacted replaces in the logs the vars bound to the fields of the model
+// tagged sensitive:"true", e.g. a password. The vars of raw statements are
+// redacted by the tables they name, once a session has used their model:
+// the columns of a model never passed to Model are unknown.
+const Redacted = "[REDACTED]"
+
+// sensitiveTables maps the lower case name of the tables of the models used
+// by the sessions to the lower case columns of their sensitive fields.
+var sensitiveTables sync.Map
+
+// registerSensitive records the sensitive columns of table, if any, for the
+// raw statements naming it.
+func registerSensitive(table *schema.Schema) {
+	columns := make(map[string]bool)
+	for _, field := range table.Fields {
+		if field.Sensitive {
+			columns[strings.ToLower(field.Column)] = true
+		}
+	}
+	if len(columns) > 0 {
+		sensitiveTables.Store(strings.ToLower(table.Name), columns)
+	}
+}
+
+// Debug returns a session logging every statement whatever the level of its
+// logger, e.g. to see the SQL of a single chain while the engine is quiet:
+// s.Debug().Where("Age > ?", 18).Find(&users). Loggers which are not a
+// log.Leveler, such as log.Discard, are replaced by log.Default().
+func (s *Session) Debug() *Session {
+	s = s.clone()
+	l, ok := s.logger.(log.Leveler)
+	if !ok {
+		l = log.Default().(log.Leveler)
+	}
+	s.logger = l.WithLevel(log.DebugLevel)
+	return s
+}
+
+// WithInterpolatedSQL makes the session log its statements with the vars
+// interpolated into the SQL, ready to be copy-pasted into a client, instead
+// of apart. Sensitive vars are redacted all the same.
+func WithInterpolatedSQL() Option {
+	return func(s *Session) {
+		s.interpolate = true
+	}
+}
+
+// sqlKV returns the key/values logging the SQL of stmt and its vars.
+func (s *Session) sqlKV(stmt *Statement) []interface{} {
+	sql := strings.TrimSpace(stmt.SQL)
+	vars := redact(stmt)
+	if s.interpolate {
+		return []interface{}{"sql", clause.Interpolate(sql, vars)}
+	}
+	return []interface{}{"sql", sql, "vars", vars}
+}
+
+// redact returns the vars of stmt, the ones bound to the sensitive fields of
+// its model, or of the tables named by its SQL, replaced by Redacted.
+func redact(stmt *Statement) []interface{} {
+	if len(stmt.Vars) == 0 {
+		return stmt.Vars
+	}
+	sensitive := make(map[string]bool)
+	if stmt.Schema != nil {
+		for _, field := range stmt.Schema.Fields {
+			if field.Sensitive {
+				sensitive[strings.ToLower(field.Column)] = true
+			}
+		}
+	}
+	if stmt.Schema == nil || stmt.Op == OpRaw {
+		for _, m := range tableRegexp.FindAllStringSubmatch(stmt.SQL, -1) {
+			if columns, ok := sensitiveTables.Load(strings.ToLower(m[1])); ok {
+				for column := range columns.(map[string]bool) {
+					sensitive[column] = true
+				}
+			}
+		}
+	}
+	if len(sensitive) == 0 {
+		return stmt.Vars
+	}
+	columns := bindColumns(stmt.SQL)
+	vars := make([]interface{}, len(stmt.Vars))
+	for i, v := range stmt.Vars {
+		if i < len(columns) && sensitive[strings.ToLower(columns[i])] {
+			v = Redacted
+		}
+		vars[i] = v
+	}
+	return vars
+}
+
+var (
+	insertRegexp = regexp.MustCompile(`(?is)^\s*INSERT\s+INTO\s+\S+\s*\(([^)]*)\)\s*VALUES\s*`)
+	// the column compared to a bind var, e.g. Password = ?, Name IN (?, ?
+	compareRegexp = regexp.MustCompile(`(?i)(\w+)["'\x60\]]?\s*(?:=|<>|!=|<=|>=|<|>|\sLIKE|\sIN\s*\()\s*(?:\?\s*,\s*)*$`)
+)
+
+// bindColumns returns the column each bind var of sql is bound to, "" if
+// unknown: the columns of INSERT ... (columns) VALUES, or the ones compared
+// to the vars in SET and WHERE.
+func bindColumns(sql string) []string {
+	var columns, inserted []string
+	values := -1 // start of VALUES
+	if m := insertRegexp.FindStringSubmatchIndex(sql); m != nil {
+		for _, c := range strings.Split(sql[m[2]:m[3]], ",") {
+			inserted = append(inserted, strings.Trim(c, " \"'`[]"))
+		}
+		values = m[1]
+	}
+	// depth and index of the column of the tuple of VALUES
+	depth, index := 0, 0
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case values >= 0 && i >= values && c == '(':
+			if depth++; depth == 1 {
+				index = 0
+			}
+		case values >= 0 && i >= values && c == ')':
+			depth--
+		case values >= 0 && i >= values && c == ',' && depth == 1:
+			index++
+		case c == '?':
+			column := ""
+			if values >= 0 && i >= values {
+				if index < len(inserted) {
+					column = inserted[index]
+				}
+			} else if m := compareRegexp.FindStringSubmatch(sql[:i]); m != nil {
+				column = m[1]
+			}
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
@@ -0,0 +1,74 @@
+package session
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fusidic/orm/pkg/log"
+)
+
+func TestBindColumns(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{"INSERT INTO Account (ID,Password) VALUES (?, ?), (?, ?)", []string{"ID", "Password", "ID", "Password"}},
+		{"UPDATE Account SET Name = ?, Password = ? WHERE ID = ?", []string{"Name", "Password", "ID"}},
+		{"SELECT * FROM Account WHERE Password IN (?, ?) AND note = '?' LIMIT ?", []string{"Password", "Password", ""}},
+		{"SELECT * FROM Account WHERE `Password` LIKE ?", []string{"Password"}},
+	}
+	for _, tt := range tests {
+		if got := bindColumns(tt.sql); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("bindColumns(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestSession_Redact(t *testing.T) {
+	var buf bytes.Buffer
+	s := New(TestDB, TestDial, WithLogger(log.NewJSON(&buf, log.InfoLevel))).Model(&Account{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(&Account{1, "secret1"}, &Account{2, "secret2"})
+	_, _ = s.Where("Password = ?", "secret1").Update("Password", "secret3")
+	_, _ = s.With(WithInterpolatedSQL()).Where("ID = ? AND Password = ?", 1, "secret3").Count()
+	out := buf.String()
+	if strings.Contains(out, "secret") {
+		t.Fatal("expect the passwords to be redacted, got", out)
+	}
+	if !strings.Contains(out, `"vars":[1001,"[REDACTED]",1002,"[REDACTED]"]`) ||
+		!strings.Contains(out, `"sql":"SELECT count(*) FROM Account WHERE ID = 1 AND Password = '[REDACTED]'"`) {
+		t.Fatal("failed to log the redacted vars, got", out)
+	}
+}
+
+func TestSession_RedactRaw(t *testing.T) {
+	var buf bytes.Buffer
+	s := New(TestDB, TestDial, WithLogger(log.NewJSON(&buf, log.InfoLevel)))
+	_ = s.Model(&Account{}).DropTable()
+	_ = s.Model(&Account{}).CreateTable()
+	_, _ = s.Raw("UPDATE Account SET Password = ? WHERE ID = ?", "secret4", 1).Exec()
+	_, _ = s.Raw("INSERT INTO account (ID, Password) VALUES (?, ?)", 5, "secret5").Exec()
+	out := buf.String()
+	if strings.Contains(out, "secret") {
+		t.Fatal("expect the passwords of raw statements to be redacted, got", out)
+	}
+	if !strings.Contains(out, `"vars":["[REDACTED]",1]`) || !strings.Contains(out, `"vars":[5,"[REDACTED]"]`) {
+		t.Fatal("failed to redact by the table of the SQL, got", out)
+	}
+}
+
+func TestSession_Debug(t *testing.T) {
+	var buf bytes.Buffer
+	s := New(TestDB, TestDial, WithLogger(log.NewJSON(&buf, log.ErrorLevel)))
+	_, _ = s.Raw("SELECT 1").Exec()
+	if buf.Len() != 0 {
+		t.Fatal("expect the engine logger to be quiet, got", buf.String())
+	}
+	_, _ = s.Debug().Raw("SELECT 2").Exec()
+	if out := buf.String(); !strings.Contains(out, `"msg":"statement","sql":"SELECT 2"`) {
+		t.Fatal("failed to log the statements of the debug session, got", out)
+	}
+}
@@ -0,0 +1,135 @@
+package session
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/fusidic/orm/pkg/clause"
+)
+
+// ErrReturningNotSupported is returned by Insert, Update and Delete with
+// Returning if the database doesn't support RETURNING, e.g. SQLite < 3.35.
+var ErrReturningNotSupported = errors.New("RETURNING is not supported by the database")
+
+type returning struct {
+	dest    interface{}
+	columns []string
+}
+
+// WithReturningSupport tells the session whether its database supports
+// RETURNING, as checked once by orm.NewEngine. Sessions without it ask the
+// dialect every time Returning is used.
+func WithReturningSupport(supported bool) Option {
+	return func(s *Session) {
+		s.supportReturning = &supported
+	}
+}
+
+// Returning makes Insert, Update and Delete return columns (all fields of
+// the model if none is given) of the changed rows with a RETURNING clause.
+// The rows are scanned into dest, a pointer to a slice of models, or back
+// into the inserted record, which must be a pointer, if dest is nil. The
+// database doesn't return the rows in any given order, so inserting several
+// records needs a dest.
+func (s *Session) Returning(dest interface{}, columns ...string) *Session {
+	s = s.clone()
+	s.returning = &returning{dest: dest, columns: columns}
+	return s
+}
+
+// setReturning adds the RETURNING clause if Returning is used, and returns
+// its columns.
+func (s *Session) setReturning() ([]string, error) {
+	if s.returning == nil {
+		return nil, nil
+	}
+	supported := s.supportReturning != nil && *s.supportReturning ||
+		s.supportReturning == nil && s.dialect.SupportReturning(s.db)
+	if !supported {
+		return nil, ErrReturningNotSupported
+	}
+	table := s.GetRefTable()
+	columns := s.returning.columns
+	if len(columns) == 0 {
+		columns = table.FieldNames
+	}
+	for _, column := range columns {
+		if table.GetField(column) == nil {
+			return nil, fmt.Errorf("returning column %s is not a field of %s", column, table.Name)
+		}
+	}
+	s.clause.Set(clause.RETURNING, columns)
+	return columns, nil
+}
+
+// checkReturning checks that the returned rows can be scanned into the
+// destination of Returning, or into the single record if it is nil.
+func (s *Session) checkReturning(records []interface{}) error {
+	if s.returning.dest != nil {
+		return nil
+	}
+	if records == nil {
+		return errors.New("returning rows need a destination")
+	}
+	// the rows can't be matched back to several records by their order
+	if len(records) > 1 {
+		return errors.New("returning rows of several records need a destination")
+	}
+	if reflect.ValueOf(records[0]).Kind() != reflect.Ptr {
+		return errors.New("returning rows can only be scanned into pointers")
+	}
+	return nil
+}
+
+// scanReturning scans the returned rows into the destination of Returning,
+// or into the single record if it is nil.
+func (s *Session) scanReturning(rows *sql.Rows, columns []string, records []interface{}) (int64, error) {
+	var destSlice reflect.Value
+	if s.returning.dest != nil {
+		destSlice = reflect.Indirect(reflect.ValueOf(s.returning.dest))
+	}
+	table := s.GetRefTable()
+	var affected int64
+	for rows.Next() {
+		var dest reflect.Value
+		if destSlice.IsValid() {
+			dest = reflect.New(destSlice.Type().Elem()).Elem()
+		} else if affected == 0 {
+			dest = reflect.Indirect(reflect.ValueOf(records[0]))
+		} else {
+			break
+		}
+		var fields []interface{}
+		for _, column := range columns {
+			fields = append(fields, dest.FieldByName(table.GetField(column).Name).Addr().Interface())
+		}
+		if err := rows.Scan(fields...); err != nil {
+			_ = rows.Close()
+			return affected, err
+		}
+		if destSlice.IsValid() {
+			destSlice.Set(reflect.Append(destSlice, dest))
+		}
+		affected++
+	}
+	return affected, rows.Close()
+}
+
+// returnsRecords reports whether the rows changed by s are scanned into the
+// destination of Returning.
+func (s *Session) returnsRecords() bool {
+	return s.returning != nil && s.returning.dest != nil
+}
+
+// changedRecords returns the last affected rows scanned into the
+// destination of Returning, for the After hooks of Update or Delete.
+func (s *Session) changedRecords(affected int64) []interface{} {
+	destSlice := reflect.Indirect(reflect.ValueOf(s.returning.dest))
+	var records []interface{}
+	for i := destSlice.Len() - int(affected); i < destSlice.Len(); i++ {
+		records = append(records, destSlice.Index(i).Addr().Interface())
+	}
+	return records
+}
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/fusidic/orm/pkg/clause"
+)
+
+func TestSession_Returning(t *testing.T) {
+	if !TestDial.SupportReturning(TestDB) {
+		t.Skip("the bundled SQLite is older than 3.35 and doesn't support RETURNING, see TestSession_ReturningSupported")
+	}
+	s := testRecordInit(t)
+	var users []User
+	affected, err := s.Where("Name = ?", "Tom").Returning(&users, "Name", "Age").Update("Age", 30)
+	if err != nil || affected != 1 || len(users) != 1 || users[0].Age != 30 {
+		t.Fatal("failed to scan returning rows, got", users, err)
+	}
+	u := &User{Name: "Amy", Age: 25}
+	if _, err := s.Returning(nil, "Age").Insert(u); err != nil || u.Age != 25 {
+		t.Fatal("failed to scan returning row into the record, got", u, err)
+	}
+	if _, err := s.Returning(nil).Insert(&User{"Bob", 1}, &User{"Cat", 2}); err == nil {
+		t.Fatal("expect returning rows of several records without a destination to fail")
+	}
+}
+
+func TestSession_ReturningNotSupported(t *testing.T) {
+	if TestDial.SupportReturning(TestDB) {
+		t.Skip("the database supports RETURNING")
+	}
+	s := testRecordInit(t)
+	var users []User
+	if _, err := s.Returning(&users).Delete(); err != ErrReturningNotSupported {
+		t.Fatal("expect ErrReturningNotSupported, got", err)
+	}
+	if count, _ := s.Count(); count != 2 {
+		t.Fatal("expect nothing to be deleted, got", count)
+	}
+}
+
+func TestSession_ReturningSupported(t *testing.T) {
+	s := testRecordInit(t)
+	callbacks := NewCallbacks()
+	// stands for a database with RETURNING: runs the UPDATE, then returns
+	// the updated rows with a SELECT
+	_ = callbacks.Update().Before("orm:update").Register("returning", func(stmt *Statement) {
+		sql, vars := stmt.Clause.Build(clause.UPDATE, clause.WHERE)
+		if _, stmt.Err = stmt.Session.Raw(sql, vars...).Exec(); stmt.Err == nil {
+			stmt.SQL, stmt.Vars = "SELECT Name, Age FROM User WHERE Age = ?", []interface{}{30}
+		}
+	})
+	// and the inserted row, with its Age doubled to tell it was scanned
+	_ = callbacks.Create().Before("orm:create").Register("returning", func(stmt *Statement) {
+		sql, vars := stmt.Clause.Build(clause.INSERT, clause.VALUES)
+		if _, stmt.Err = stmt.Session.Raw(sql, vars...).Exec(); stmt.Err == nil {
+			stmt.SQL, stmt.Vars = "SELECT Age * 2 FROM User WHERE Name = ?", []interface{}{vars[0]}
+		}
+	})
+	supported := s.With(WithCallbacks(callbacks), WithReturningSupport(true))
+	var users []User
+	affected, err := supported.Where("Name = ?", "Tom").Returning(&users, "Name", "Age").Update("Age", 30)
+	if err != nil || affected != 1 || len(users) != 1 || users[0] != (User{"Tom", 30}) {
+		t.Fatal("failed to scan returning rows, got", users, err)
+	}
+
+	u := &User{Name: "Amy", Age: 25}
+	if affected, err := supported.Returning(nil, "Age").Insert(u); err != nil || affected != 1 || u.Age != 50 {
+		t.Fatal("failed to scan returning row into the record, got", u, err)
+	}
+	if _, err := supported.Returning(nil, "Age").Insert(&User{"Bob", 1}, &User{"Cat", 2}); err == nil {
+		t.Fatal("expect returning rows of several records without a destination to fail")
+	}
+	if count, _ := s.Count(); count != 3 {
+		t.Fatal("expect the records without a destination not to be inserted, got", count)
+	}
+
+	if _, err := s.With(WithReturningSupport(false)).Returning(&users).Delete(); err != ErrReturningNotSupported {
+		t.Fatal("expect ErrReturningNotSupported, got", err)
+	}
+}
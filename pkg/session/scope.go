@@ -0,0 +1,43 @@
+package session
+
+import "reflect"
+
+// DefaultScoper is implemented by models with a default scope, which is
+// applied to every Find, Count, Update and Delete of the model unless the
+// session is Unscoped, e.g. to hide soft deleted records:
+//
+//	func (u *User) DefaultScope(s *Session) *Session {
+//		return s.Where("Deleted = ?", false)
+//	}
+type DefaultScoper interface {
+	DefaultScope(s *Session) *Session
+}
+
+// Scopes applies reusable query fragments to the session in order.
+func (s *Session) Scopes(fns ...func(*Session) *Session) *Session {
+	for _, fn := range fns {
+		s = fn(s)
+	}
+	return s
+}
+
+// Unscoped skips the default scope of the model.
+func (s *Session) Unscoped() *Session {
+	s = s.clone()
+	s.unscoped = true
+	return s
+}
+
+// applyDefaultScope returns a derived session with the default scope of
+// the model applied, if any.
+func (s *Session) applyDefaultScope() *Session {
+	s = s.clone()
+	if s.unscoped {
+		return s
+	}
+	modelType := reflect.Indirect(reflect.ValueOf(s.GetRefTable().Model)).Type()
+	if scoper, ok := reflect.New(modelType).Interface().(DefaultScoper); ok {
+		return scoper.DefaultScope(s)
+	}
+	return s
+}
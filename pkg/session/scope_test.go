@@ -0,0 +1,45 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/fusidic/orm/pkg/clause"
+)
+
+type Member struct {
+	Name   string `orm:"PRIMARY KEY"`
+	Age    int
+	Active bool
+}
+
+func (m *Member) DefaultScope(s *Session) *Session {
+	return s.Where("Active = ?", true)
+}
+
+func Adult(s *Session) *Session {
+	return s.Where(clause.Gte("Age", 18))
+}
+
+func TestSession_Scopes(t *testing.T) {
+	s := NewSession().Model(&Member{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(&Member{"Tom", 18, true}, &Member{"Sam", 25, false}, &Member{"Jack", 12, true})
+
+	var members []Member
+	if err := s.Scopes(Adult).Find(&members); err != nil || len(members) != 1 || members[0].Name != "Tom" {
+		t.Fatal("failed to apply scopes, got", members)
+	}
+	if count, _ := s.Count(); count != 2 {
+		t.Fatal("failed to apply default scope to count, got", count)
+	}
+	if count, _ := s.Unscoped().Count(); count != 3 {
+		t.Fatal("failed to skip default scope, got", count)
+	}
+	if affected, _ := s.Update("Age", 30); affected != 2 {
+		t.Fatal("failed to apply default scope to update, got", affected)
+	}
+	if affected, _ := s.Delete(); affected != 2 {
+		t.Fatal("failed to apply default scope to delete, got", affected)
+	}
+}
@@ -0,0 +1,63 @@
+package session
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/fusidic/orm/pkg/log"
+)
+
+// WithSlowThreshold makes the session log the statements taking threshold
+// or longer to the slow query log of its logger if it is a log.SlowLogger,
+// see log.SetSlowOutput for the default one, or as warnings, with their
+// duration, rows affected, the file:line calling the orm and the SQL with
+// its vars, sensitive ones redacted. The duration of a query is the time
+// until its first row is ready, not the one spent scanning the rows.
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(s *Session) {
+		s.slowThreshold = threshold
+	}
+}
+
+// logSlow logs stmt if it is slow.
+func (s *Session) logSlow(stmt *Statement) {
+	if s.slowThreshold <= 0 || stmt.Duration < s.slowThreshold {
+		return
+	}
+	// rows affected are unknown for queries
+	rows := int64(-1)
+	if stmt.Result != nil {
+		if n, err := stmt.Result.RowsAffected(); err == nil {
+			rows = n
+		}
+	}
+	kv := append([]interface{}{"caller", caller(), "duration", stmt.Duration, "rows", rows}, s.sqlKV(stmt)...)
+	if l, ok := s.logger.(log.SlowLogger); ok {
+		l.Slow(stmt.Context, "slow query", kv...)
+		return
+	}
+	s.logger.Warn(stmt.Context, "slow query", kv...)
+}
+
+// ormPrefix is the prefix of the functions of the orm packages.
+var ormPrefix = strings.TrimSuffix(reflect.TypeOf(Session{}).PkgPath(), "session")
+
+// caller returns the file:line of the first caller outside the orm
+// packages, their tests are outside as well.
+func caller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, ormPrefix) || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
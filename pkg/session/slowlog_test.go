@@ -0,0 +1,31 @@
+package session
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fusidic/orm/pkg/log"
+)
+
+func TestSession_SlowLog(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetSlowOutput(&buf)
+	defer log.SetSlowOutput(os.Stdout)
+
+	s := New(TestDB, TestDial, WithSlowThreshold(time.Hour)).Model(&User{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	if _, _ = s.Insert(user1, user2); buf.Len() != 0 {
+		t.Fatal("expect no slow query, got", buf.String())
+	}
+
+	s = New(TestDB, TestDial, WithSlowThreshold(time.Nanosecond)).Model(&User{})
+	_, _ = s.Where("Age > ?", 20).Delete()
+	out := buf.String()
+	if !strings.Contains(out, "slowlog_test.go:") || !strings.Contains(out, "rows=1 sql=DELETE FROM User WHERE Age > ? vars=[20]") {
+		t.Fatal("failed to log slow query, got", out)
+	}
+}
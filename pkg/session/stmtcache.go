@@ -0,0 +1,156 @@
+package session
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+)
+
+// StmtCache is a LRU cache of prepared statements keyed by their SQL, so
+// that the statements run again and again are only sent to the database
+// once. It is shared by the sessions it is set on with WithStmtCache,
+// usually all sessions of an engine:
+//
+//	cache := session.NewStmtCache(128)
+//	engine, _ := orm.NewEngine("sqlite3", "orm.db", session.WithStmtCache(cache))
+//
+// Inside a transaction the cached statement is bound to it by tx.Stmt, and
+// a statement which isn't cached is run without being prepared. The
+// cache is purged by statements changing the schema, like CreateTable,
+// DropTable and Migrate.
+type StmtCache struct {
+	mu      sync.Mutex
+	size    int
+	ll      *list.List // of *cachedStmt, most recently used first
+	items   map[string]*list.Element
+	hits    uint64
+	misses  uint64
+	evicted uint64
+}
+
+// StmtCacheStats are the statistics of a StmtCache.
+type StmtCacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Evicted uint64
+	Len     int // number of statements in the cache
+}
+
+type cachedStmt struct {
+	query   string
+	stmt    *sql.Stmt
+	refs    int // number of running statements
+	evicted bool
+}
+
+// NewStmtCache returns a cache holding up to size statements, 64 if size is
+// not positive.
+func NewStmtCache(size int) *StmtCache {
+	if size <= 0 {
+		size = 64
+	}
+	return &StmtCache{size: size, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// WithStmtCache makes the session run its statements through the cache.
+func WithStmtCache(c *StmtCache) Option {
+	return func(s *Session) {
+		s.stmtCache = c
+	}
+}
+
+// Stats returns the statistics of the cache.
+func (c *StmtCache) Stats() StmtCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return StmtCacheStats{Hits: c.hits, Misses: c.misses, Evicted: c.evicted, Len: c.ll.Len()}
+}
+
+// Purge closes and removes all statements.
+func (c *StmtCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for e := c.ll.Front(); e != nil; {
+		next := e.Next()
+		c.evict(e)
+		e = next
+	}
+}
+
+// lookup returns the cached statement of query, and a func to call once it
+// is run.
+func (c *StmtCache) lookup(query string) (*sql.Stmt, func(), bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[query]
+	if !ok {
+		c.misses++
+		return nil, nil, false
+	}
+	c.hits++
+	c.ll.MoveToFront(e)
+	cs := e.Value.(*cachedStmt)
+	cs.refs++
+	return cs.stmt, func() { c.release(cs) }, true
+}
+
+// get returns the statement of query, prepared on db if it isn't cached,
+// and a func to call once it is run.
+func (c *StmtCache) get(ctx context.Context, db *sql.DB, query string) (*sql.Stmt, func(), error) {
+	if stmt, release, ok := c.lookup(query); ok {
+		return stmt, release, nil
+	}
+
+	// prepare without the lock, another goroutine may do it as well
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cs := &cachedStmt{query: query, stmt: stmt, refs: 1}
+	if e, ok := c.items[query]; ok {
+		c.evict(e)
+	}
+	c.items[query] = c.ll.PushFront(cs)
+	for c.ll.Len() > c.size {
+		c.evict(c.ll.Back())
+		c.evicted++
+	}
+	return stmt, func() { c.release(cs) }, nil
+}
+
+// evict removes the element, its statement is closed once it isn't run
+// anymore. c.mu must be held.
+func (c *StmtCache) evict(e *list.Element) {
+	cs := e.Value.(*cachedStmt)
+	c.ll.Remove(e)
+	delete(c.items, cs.query)
+	cs.evicted = true
+	if cs.refs == 0 {
+		_ = cs.stmt.Close()
+	}
+}
+
+func (c *StmtCache) release(cs *cachedStmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cs.refs--
+	if cs.evicted && cs.refs == 0 {
+		_ = cs.stmt.Close()
+	}
+}
+
+// isDDL reports whether query changes the schema, which invalidates the
+// prepared statements.
+func isDDL(query string) bool {
+	verb := strings.ToUpper(strings.TrimSpace(query))
+	return strings.HasPrefix(verb, "CREATE") || strings.HasPrefix(verb, "DROP") || strings.HasPrefix(verb, "ALTER")
+}
+
+// cacheable reports whether query can be prepared as one statement.
+func cacheable(query string) bool {
+	return !isDDL(query) && !strings.Contains(strings.TrimRight(query, "; \t\n"), ";")
+}
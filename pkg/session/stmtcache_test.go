@@ -0,0 +1,86 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestStmtCache(t *testing.T) {
+	cache := NewStmtCache(2)
+	s := New(TestDB, TestDial, WithStmtCache(cache)).Model(&User{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(user1, user2)
+
+	for i := 0; i < 3; i++ {
+		if count, err := s.Count(); err != nil || count != 2 {
+			t.Fatal("failed to count with prepared statement", count, err)
+		}
+	}
+	if stats := cache.Stats(); stats.Hits != 2 || stats.Len != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	var users []User
+	if err := s.Where("Age > ?", 20).Find(&users); err != nil || len(users) != 1 {
+		t.Fatal("failed to find with prepared statement", users, err)
+	}
+	if stats := cache.Stats(); stats.Evicted != 1 || stats.Len != 2 {
+		t.Fatalf("expect least recently used statement to be evicted, got %+v", stats)
+	}
+
+	tx := s.clone()
+	if err := tx.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = tx.Insert(user3)
+	if count, _ := tx.Count(); count != 3 {
+		t.Fatal("expect prepared statement to run in transaction, got", count)
+	}
+	_ = tx.Rollback()
+	if count, _ := s.Count(); count != 2 {
+		t.Fatal("failed to roll back prepared statement, got", count)
+	}
+
+	_ = s.DropTable()
+	if stats := cache.Stats(); stats.Len != 0 {
+		t.Fatalf("expect schema change to purge the cache, got %+v", stats)
+	}
+}
+
+func TestStmtCacheSingleConn(t *testing.T) {
+	db, err := sql.Open("sqlite3", "../../orm.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	cache := NewStmtCache(8)
+	s := New(db, TestDial, WithStmtCache(cache)).Model(&User{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(user1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tx := s.WithContext(ctx)
+	if err := tx.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	// neither the cached INSERT nor the COUNT which isn't cached may need a
+	// second connection of the pool
+	if _, err := tx.Insert(user2); err != nil {
+		t.Fatal("failed to insert in transaction on a single connection, got", err)
+	}
+	if count, err := tx.Count(); err != nil || count != 2 {
+		t.Fatal("failed to count in transaction on a single connection, got", count, err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Len != 1 {
+		t.Fatalf("expect only the statement run outside the transaction to be cached, got %+v", stats)
+	}
+}
@@ -1,19 +1,23 @@
 package session
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
 
-	"github.com/fusidic/orm/pkg/log"
 	"github.com/fusidic/orm/pkg/schema"
 )
 
-// Model 方法用于给 refTable 赋值
+// Model 方法用于给 refTable 赋值，返回一个新的 Session
 func (s *Session) Model(value interface{}) *Session {
+	s = s.clone()
 	// nil or different model, update refTable
 	if s.refTable == nil || reflect.TypeOf(value) != reflect.TypeOf(s.refTable.Model) {
 		s.refTable = schema.Parse(value, s.dialect)
+		s.refTable.Hooks = hooksOf(value)
+		registerSensitive(s.refTable)
 	}
 	return s
 }
@@ -21,7 +25,7 @@ func (s *Session) Model(value interface{}) *Session {
 // GetRefTable returns a Schema instance that contains all parsed fields.
 func (s *Session) GetRefTable() *schema.Schema {
 	if s.refTable == nil {
-		log.Error("Model is not set")
+		s.logger.Error(s.Context(), "Model is not set")
 	}
 	return s.refTable
 }
@@ -31,11 +35,18 @@ func (s *Session) CreateTable() error {
 	table := s.GetRefTable()
 	var columns []string
 	for _, field := range table.Fields {
-		columns = append(columns, fmt.Sprintf("%s %s %s", field.Name, field.Type, field.Tag))
+		columns = append(columns, field.Definition(s.dialect))
 	}
 	desc := strings.Join(columns, ",")
-	_, err := s.Raw(fmt.Sprintf("CREATE TABLE %s (%s);", table.Name, desc)).Exec()
-	return err
+	if _, err := s.Raw(fmt.Sprintf("CREATE TABLE %s (%s);", table.Name, desc)).Exec(); err != nil {
+		return err
+	}
+	for _, sql := range table.IndexSQL() {
+		if _, err := s.Raw(sql).Exec(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // DropTable drop a table in database
@@ -44,11 +55,21 @@ func (s *Session) DropTable() error {
 	return err
 }
 
-// HasTable check if the database has the table
+// HasTable check if the database has the table, it is false in dry run.
 func (s *Session) HasTable() bool {
-	sql, values := s.dialect.TableExistSQL(s.GetRefTable().Name)
-	row := s.Raw(sql, values...).QueryRow()
+	exists, _ := s.hasTable()
+	return exists
+}
+
+func (s *Session) hasTable() (bool, error) {
+	name := s.GetRefTable().Name
+	query, values := s.dialect.TableExistSQL(name)
 	var tmp string
-	_ = row.Scan(&tmp)
-	return tmp == s.GetRefTable().Name
+	if err := s.Raw(query, values...).QueryRow().Scan(&tmp); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return tmp == name, nil
 }
@@ -5,7 +5,7 @@ import (
 )
 
 type User struct {
-	Name string `geeorm:"PRIMARY KEY"`
+	Name string `orm:"primaryKey"`
 	Age  int
 }
 
@@ -17,3 +17,50 @@ func TestSession_CreateTable(t *testing.T) {
 		t.Fatal("Failed to create table User")
 	}
 }
+
+type Profile struct {
+	ID    int    `orm:"primaryKey;autoIncrement"`
+	Name  string `orm:"column:user_name;not null;unique;index"`
+	Cache string `orm:"-"`
+}
+
+func TestSession_CreateTableFromTag(t *testing.T) {
+	s := NewSession().Model(&Profile{})
+	_ = s.DropTable()
+	if err := s.CreateTable(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Insert(&Profile{Name: "Tom", Cache: "x"}, &Profile{Name: "Sam"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Insert(&Profile{Name: "Tom"}); err == nil {
+		t.Fatal("expect the unique constraint to fail")
+	}
+	var profiles []Profile
+	if err := s.Where("user_name = ?", "Sam").Find(&profiles); err != nil || len(profiles) != 1 ||
+		profiles[0].ID != 2 || profiles[0].Name != "Sam" || profiles[0].Cache != "" {
+		t.Fatal("failed to query the renamed column, got", profiles, err)
+	}
+	var index string
+	_ = s.Raw("SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'Profile' AND name LIKE 'idx_%'").QueryRow().Scan(&index)
+	if index != "idx_Profile_user_name" {
+		t.Fatal("failed to create the index, got", index)
+	}
+}
+
+type Event struct {
+	ID   int64 `orm:"primaryKey;autoIncrement"`
+	Name string
+}
+
+func TestSession_CreateTableInt64Key(t *testing.T) {
+	s := NewSession().Model(&Event{})
+	_ = s.DropTable()
+	if err := s.CreateTable(); err != nil {
+		t.Fatal("failed to create table with an int64 auto increment key", err)
+	}
+	a, b := &Event{Name: "a"}, &Event{Name: "b"}
+	if _, err := s.Insert(a, b); err != nil || a.ID != 1 || b.ID != 2 {
+		t.Fatal("failed to generate int64 keys, got", a, b, err)
+	}
+}
@@ -0,0 +1,91 @@
+package session
+
+import (
+	"context"
+
+	"github.com/fusidic/orm/pkg/trace"
+)
+
+// WithTracer makes the session start a span around every statement and
+// transaction, trace.Noop by default.
+func WithTracer(t trace.Tracer) Option {
+	return func(s *Session) {
+		s.tracer = t
+	}
+}
+
+// WithContext returns a session running its statements with ctx, whose
+// span is the parent of the spans of the session.
+func (s *Session) WithContext(ctx context.Context) *Session {
+	s = s.clone()
+	s.ctx = ctx
+	return s
+}
+
+// Context returns the context of the session, the one of its transaction
+// once it begins.
+func (s *Session) Context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// runStatement passes stmt through the callbacks of its operation in a
+// span. The span is ended here unless the caller counts the rows scanned
+// and ends it by endSpan.
+func (s *Session) runStatement(stmt *Statement, scanned bool) {
+	stmt.Context, stmt.span = s.tracer.Start(s.Context(), "orm."+stmt.Op.String())
+	s.callbacks.processors[stmt.Op].run(stmt)
+	if !scanned || stmt.Err != nil {
+		rows := int64(-1)
+		if stmt.Result != nil {
+			rows, _ = stmt.Result.RowsAffected()
+		}
+		stmt.endSpan(rows, stmt.Err)
+	}
+}
+
+// endSpan ends the span of the statement with its attributes, rows is -1
+// if unknown.
+func (stmt *Statement) endSpan(rows int64, err error) {
+	if stmt.span == nil {
+		return
+	}
+	attrs := []trace.Attribute{
+		trace.Attr(trace.AttrSQL, stmt.SQL),
+		trace.Attr(trace.AttrOperation, stmt.Op.String()),
+		trace.Attr(trace.AttrRows, rows),
+	}
+	if stmt.Schema != nil {
+		attrs = append(attrs, trace.Attr(trace.AttrTable, stmt.Schema.Name))
+	}
+	stmt.span.SetAttributes(attrs...)
+	if err != nil {
+		stmt.span.RecordError(err)
+	}
+	stmt.span.End()
+	stmt.span = nil
+}
+
+// startTx starts the span of a transaction, the statements run in it are
+// its children.
+func (s *Session) startTx() {
+	s.txParent = s.ctx
+	s.ctx, s.txSpan = s.tracer.Start(s.Context(), "orm.transaction")
+}
+
+// endTx ends the span of the transaction with its outcome, commit or
+// rollback, and restores the context before it.
+func (s *Session) endTx(outcome string, err error) {
+	if s.txSpan == nil {
+		return
+	}
+	s.ctx = s.txParent
+	s.txSpan.SetAttributes(trace.Attr(trace.AttrOperation, outcome))
+	if err != nil {
+		s.txSpan.RecordError(err)
+	}
+	s.txSpan.End()
+	s.txSpan = nil
+}
@@ -0,0 +1,48 @@
+package session
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fusidic/orm/pkg/trace"
+)
+
+func TestSession_Tracer(t *testing.T) {
+	r := &trace.Recorder{}
+	ctx, root := r.Start(context.Background(), "request")
+	s := New(TestDB, TestDial, WithTracer(r)).WithContext(ctx).Model(&User{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	r.Reset()
+
+	tx := s.clone()
+	_ = tx.Begin()
+	_, _ = tx.Insert(user1, user2)
+	var users []User
+	_ = tx.Where("Age > ?", 10).Find(&users)
+	_, _ = tx.Raw("SELECT * FROM Nothing").QueryRows()
+	_ = tx.Commit()
+	root.End()
+
+	spans := r.Spans()
+	if len(spans) != 4 {
+		t.Fatal("expect transaction and 3 statement spans, got", len(spans))
+	}
+	txSpan := spans[0]
+	if txSpan.Name != "orm.transaction" || txSpan.Parent == nil || txSpan.Parent.Name != "request" ||
+		txSpan.Attributes[trace.AttrOperation] != "commit" || !txSpan.Ended {
+		t.Fatal("failed to trace transaction", txSpan)
+	}
+	insert, find, raw := spans[1], spans[2], spans[3]
+	if insert.Name != "orm.create" || insert.Parent != txSpan || insert.Attributes[trace.AttrRows] != int64(2) ||
+		insert.Attributes[trace.AttrTable] != "User" {
+		t.Fatal("failed to trace insert", insert.Attributes)
+	}
+	if find.Name != "orm.query" || find.Attributes[trace.AttrRows] != int64(2) ||
+		find.Attributes[trace.AttrSQL] != "SELECT Name,Age FROM User WHERE Age > ?" || !find.Ended {
+		t.Fatal("failed to trace find", find.Attributes)
+	}
+	if raw.Name != "orm.raw" || len(raw.Errors) != 1 || !raw.Ended {
+		t.Fatal("failed to trace error", raw.Attributes, raw.Errors)
+	}
+}
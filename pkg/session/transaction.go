@@ -1,32 +1,69 @@
 package session
 
-import "github.com/fusidic/orm/pkg/log"
+import (
+	"database/sql"
+	"sync/atomic"
+)
 
 // Begin a transcation.
 func (s *Session) Begin() (err error) {
-	log.Info("transaction begin")
-	// 调用 s.db.Begin() 得到 *sql.Tx 对象并赋值给 s.tx
-	if s.tx, err = s.db.Begin(); err != nil {
-		log.Error(err)
+	s.logger.Info(s.Context(), "transaction begin")
+	s.startTx()
+	// 调用 s.db.BeginTx() 得到 *sql.Tx 对象并赋值给 s.tx
+	if s.tx, err = s.db.BeginTx(s.Context(), nil); err != nil {
+		s.logger.Error(s.Context(), "failed to begin transaction", "error", err)
+		s.endTx("begin", err)
 		return
 	}
+	if s.queryCache != nil {
+		s.txChanges = &txChanges{}
+	}
+	if s.metrics != nil {
+		atomic.AddUint64(&s.metrics.txBegun, 1)
+	}
 	return
 }
 
-// Commit a transaction.
+// Commit a transaction. The session is out of it afterwards, even if it
+// fails, and Commit or Rollback again return sql.ErrTxDone.
 func (s *Session) Commit() (err error) {
-	log.Info("transcation commit")
-	if err = s.tx.Commit(); err != nil {
-		log.Error(err)
+	if s.tx == nil {
+		return sql.ErrTxDone
+	}
+	s.logger.Info(s.Context(), "transaction commit")
+	err = s.tx.Commit()
+	changes := s.txChanges
+	s.tx, s.txChanges = nil, nil
+	s.endTx("commit", err)
+	if err != nil {
+		s.logger.Error(s.Context(), "failed to commit", "error", err)
+		return
+	}
+	if changes != nil {
+		changes.flush(s.queryCache)
+	}
+	if s.metrics != nil {
+		atomic.AddUint64(&s.metrics.txCommitted, 1)
 	}
 	return
 }
 
-// Rollback a transaction.
+// Rollback a transaction. The session is out of it afterwards, as after
+// Commit.
 func (s *Session) Rollback() (err error) {
-	log.Info("transaction rollback")
-	if err = s.tx.Rollback(); err != nil {
-		log.Error(err)
+	if s.tx == nil {
+		return sql.ErrTxDone
+	}
+	s.logger.Info(s.Context(), "transaction rollback")
+	err = s.tx.Rollback()
+	s.tx, s.txChanges = nil, nil
+	s.endTx("rollback", err)
+	if err != nil {
+		s.logger.Error(s.Context(), "failed to rollback", "error", err)
+		return
+	}
+	if s.metrics != nil {
+		atomic.AddUint64(&s.metrics.txRolledBack, 1)
 	}
 	return
 }
@@ -0,0 +1,92 @@
+package session
+
+import (
+	"reflect"
+
+	"github.com/fusidic/orm/pkg/schema"
+)
+
+// Validator is implemented by models with custom validation rules. Insert
+// calls it on every record after the rules of the validate tags, Update on
+// every record matched with the updated values set. An error is added to
+// the *schema.ValidationError, whose fields it may also return.
+type Validator interface {
+	Validate(s *Session) error
+}
+
+func isValidator(model interface{}) bool {
+	_, ok := reflect.New(reflect.Indirect(reflect.ValueOf(model)).Type()).Interface().(Validator)
+	return ok
+}
+
+// validate checks record against the rules of s's table and its own
+// Validate, it returns nil or a *schema.ValidationError.
+func (s *Session) validate(record interface{}) error {
+	table := s.GetRefTable()
+	if table.Err != nil {
+		return table.Err
+	}
+	return s.validateCustom(record, table.Validate(record))
+}
+
+// validateValues checks the values of Update against the rules of their
+// fields, and records, the ones matched, against their own Validate once
+// the values are set on a copy of them. Values of other types than their
+// fields, like SQL expressions, are left out.
+func (s *Session) validateValues(values map[string]interface{}, records []interface{}) error {
+	table := s.GetRefTable()
+	if table.Err != nil {
+		return table.Err
+	}
+	err := table.ValidateValues(values)
+	if s.dryRun != nil || !isValidator(table.Model) {
+		return err
+	}
+	for _, record := range records {
+		updated := reflect.New(reflect.Indirect(reflect.ValueOf(record)).Type())
+		updated.Elem().Set(reflect.Indirect(reflect.ValueOf(record)))
+		for column, value := range values {
+			field := table.GetField(column)
+			if field == nil {
+				continue
+			}
+			dest := updated.Elem().FieldByName(field.Name)
+			v := reflect.ValueOf(value)
+			switch {
+			case value == nil:
+				dest.Set(reflect.Zero(dest.Type()))
+			case v.Type().AssignableTo(dest.Type()):
+				dest.Set(v)
+			case v.Type().ConvertibleTo(dest.Type()) && v.Kind() != reflect.String && dest.Kind() != reflect.String:
+				dest.Set(v.Convert(dest.Type()))
+			}
+		}
+		if err = s.validateCustom(updated.Interface(), err); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// validateCustom adds the error of the Validate of record, if any, to err,
+// the error of the rules of the tags.
+func (s *Session) validateCustom(record interface{}, err error) error {
+	v, ok := record.(Validator)
+	if !ok {
+		return err
+	}
+	custom := v.Validate(s)
+	if custom == nil {
+		return err
+	}
+	verr, _ := err.(*schema.ValidationError)
+	if verr == nil {
+		verr = &schema.ValidationError{Model: s.GetRefTable().Name}
+	}
+	if cerr, ok := custom.(*schema.ValidationError); ok {
+		verr.Fields = append(verr.Fields, cerr.Fields...)
+	} else {
+		verr.Fields = append(verr.Fields, &schema.FieldError{Err: custom})
+	}
+	return verr
+}
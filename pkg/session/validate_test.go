@@ -0,0 +1,105 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fusidic/orm/pkg/schema"
+)
+
+type Signup struct {
+	Email    string `orm:"PRIMARY KEY" validate:"required,email"`
+	Password string `validate:"min=8"`
+	Confirm  string
+	Age      int `validate:"min=13,max=130"`
+}
+
+// Validate adds a rule across fields to the ones of the tags.
+func (s *Signup) Validate(_ *Session) error {
+	if s.Password != s.Confirm {
+		return &schema.ValidationError{Fields: []*schema.FieldError{
+			{Field: "Confirm", Rule: "Validate", Err: errors.New("does not match Password")},
+		}}
+	}
+	return nil
+}
+
+var _ Validator = (*Signup)(nil)
+
+func TestSession_Validate(t *testing.T) {
+	s := NewSession().Model(&Signup{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+
+	valid := &Signup{Email: "tom@example.com", Password: "password", Confirm: "password", Age: 20}
+	invalid := &Signup{Email: "tom", Password: "short", Confirm: "other", Age: 20}
+	_, err := s.Insert(valid, invalid)
+	var verr *schema.ValidationError
+	if !errors.As(err, &verr) || len(verr.Fields) != 3 {
+		t.Fatal("expect every failing field to be listed, got", err)
+	}
+	if count, _ := s.Count(); count != 0 {
+		t.Fatal("expect nothing to be inserted, got", count)
+	}
+	if _, err := s.Insert(valid); err != nil {
+		t.Fatal("failed to insert valid record", err)
+	}
+
+	_, err = s.Where("Email = ?", valid.Email).Update("Age", 7)
+	if !errors.As(err, &verr) || verr.Fields[0].Field != "Age" {
+		t.Fatal("expect update to be validated, got", err)
+	}
+	if affected, err := s.Where("Email = ?", valid.Email).Update("Age", 30); err != nil || affected != 1 {
+		t.Fatal("failed to update valid values", err)
+	}
+	if _, err := s.Increment("Age", 1); err != nil {
+		t.Fatal("expect expressions not to be validated, got", err)
+	}
+	_, err = s.Where("Email = ?", valid.Email).Update("Password", "new password")
+	if !errors.As(err, &verr) || verr.Fields[0].Field != "Confirm" {
+		t.Fatal("expect Validate to check the updated record, got", err)
+	}
+	if _, err := s.Where("Email = ?", valid.Email).Update("Password", "new password", "Confirm", "new password"); err != nil {
+		t.Fatal("failed to update valid record", err)
+	}
+}
+
+type Ticket struct {
+	ID   int    `orm:"PRIMARY KEY"`
+	Code string `validate:"required,len=4"`
+}
+
+// BeforeInsert sets the code, which is validated after it.
+func (t *Ticket) BeforeInsert(_ *Session) error {
+	if t.Code == "" {
+		t.Code = fmt.Sprintf("T%03d", t.ID)
+	}
+	return nil
+}
+
+func TestSession_ValidateAfterHook(t *testing.T) {
+	s := NewSession().Model(&Ticket{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	if _, err := s.Insert(&Ticket{ID: 1}); err != nil {
+		t.Fatal("expect the code set by BeforeInsert to be validated, got", err)
+	}
+	if _, err := s.Insert(&Ticket{ID: 2, Code: "T2"}); err == nil {
+		t.Fatal("expect invalid code to fail")
+	}
+}
+
+func TestSession_InvalidRule(t *testing.T) {
+	type Coupon struct {
+		Code string `validate:"requird"`
+	}
+	s := NewSession().Model(&Coupon{})
+	if err := s.CreateTable(); err == nil || !strings.Contains(err.Error(), "unknown rule requird") {
+		t.Fatal("expect the unknown rule to fail the statements, got", err)
+	}
+	if _, err := s.Insert(&Coupon{"x"}); err == nil {
+		t.Fatal("expect the unknown rule to fail Insert")
+	}
+}
@@ -0,0 +1,119 @@
+// Package trace is the tracing interface of the orm, the sessions start a
+// span around every statement and transaction. Plug in a tracing SDK by
+// implementing Tracer, the orm doesn't depend on any.
+package trace
+
+import (
+	"context"
+	"sync"
+)
+
+// Attribute keys set by the sessions
+const (
+	AttrSQL       = "db.statement"
+	AttrTable     = "db.table"
+	AttrOperation = "db.operation"
+	AttrRows      = "db.rows"
+)
+
+// Attribute is a key value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr returns an attribute.
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Tracer starts spans.
+type Tracer interface {
+	// Start starts a span, child of the span of ctx if any, and returns a
+	// context holding it.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a unit of work, ended once.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Noop is the default tracer, its spans do nothing.
+var Noop Tracer = noop{}
+
+type noop struct{}
+
+func (noop) Start(ctx context.Context, _ string) (context.Context, Span) { return ctx, noop{} }
+func (noop) SetAttributes(...Attribute)                                  {}
+func (noop) RecordError(error)                                           {}
+func (noop) End()                                                        {}
+
+// Recorder is a tracer keeping its spans in memory, meant for tests.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// RecordedSpan is a span started by a Recorder.
+type RecordedSpan struct {
+	recorder   *Recorder
+	Name       string
+	Parent     *RecordedSpan
+	Attributes map[string]interface{}
+	Errors     []error
+	Ended      bool
+}
+
+type spanKey struct{}
+
+var _ Tracer = (*Recorder)(nil)
+
+// Start implements Tracer.
+func (r *Recorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(spanKey{}).(*RecordedSpan)
+	span := &RecordedSpan{recorder: r, Name: name, Parent: parent, Attributes: make(map[string]interface{})}
+	r.mu.Lock()
+	r.spans = append(r.spans, span)
+	r.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Spans returns the spans started so far, in order.
+func (r *Recorder) Spans() []*RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*RecordedSpan{}, r.spans...)
+}
+
+// Reset forgets the spans started so far.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = nil
+}
+
+// SetAttributes implements Span.
+func (s *RecordedSpan) SetAttributes(attrs ...Attribute) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	for _, attr := range attrs {
+		s.Attributes[attr.Key] = attr.Value
+	}
+}
+
+// RecordError implements Span.
+func (s *RecordedSpan) RecordError(err error) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.Errors = append(s.Errors, err)
+}
+
+// End implements Span.
+func (s *RecordedSpan) End() {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.Ended = true
+}
@@ -0,0 +1,36 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	r := &Recorder{}
+	ctx, parent := r.Start(context.Background(), "parent")
+	_, child := r.Start(ctx, "child")
+	child.SetAttributes(Attr(AttrRows, 1))
+	child.RecordError(errors.New("failed"))
+	child.End()
+	parent.End()
+
+	spans := r.Spans()
+	if len(spans) != 2 || spans[1].Parent != spans[0] || spans[0].Parent != nil {
+		t.Fatal("failed to record parent of spans")
+	}
+	if spans[1].Attributes[AttrRows] != 1 || len(spans[1].Errors) != 1 || !spans[1].Ended || !spans[0].Ended {
+		t.Fatal("failed to record span")
+	}
+	r.Reset()
+	if len(r.Spans()) != 0 {
+		t.Fatal("failed to reset")
+	}
+}
+
+func TestNoop(t *testing.T) {
+	ctx := context.Background()
+	if got, span := Noop.Start(ctx, "noop"); got != ctx || span == nil {
+		t.Fatal("expect noop span")
+	}
+}